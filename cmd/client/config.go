@@ -1,40 +1,319 @@
 package main
 
 import (
+	"cmp"
+	"errors"
 	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mszalbach/sirigo/internal/httputils"
+	"github.com/mszalbach/sirigo/internal/profile"
+	"github.com/mszalbach/sirigo/internal/siri"
+	"gopkg.in/yaml.v3"
 )
 
+const usage = `Usage: sirigo [command] [options]
+
+Without a command the TUI is started.
+
+Commands:
+  send    Send a template to the SIRI server and print the response
+  listen  Listen for SIRI server requests and print them
+  run     Run scenario files with multiple steps and report the results
+  server  Simulate a SIRI server which accepts subscriptions and sends DataReadyNotifications
+
+Use sirigo [command] -h to see the options of a command.
+
+Options are read from the config file, overridden by SIRIGO_* environment variables like SIRIGO_URL
+and by the command line flags. The config file is a YAML file with the flag names as keys.
+
+Options:
+`
+
+// envPrefix is the prefix of the environment variables which override the config file
+const envPrefix = "SIRIGO_"
+
 type config struct {
+	configFile      string
 	url             string
 	clientRef       string
 	clientPort      string
+	consumerAddress string
 	templateDir     string
 	autoresponseDir string
 	logFile         string
 	httpLogFile     string
+	exchangeLogFile string
+	historyFile     string
+	schemaDir       string
+	strict          bool
+	protocol        string
+	fetched         bool
+	profilesFile    string
+	profileName     string
+	clientTLS       httputils.TLSConfig
+	listenerTLS     httputils.ServerTLSConfig
+	timeout         time.Duration
+	retry           httputils.RetryPolicy
+	gzip            bool
+	listenerTimeout time.Duration
+	pushTargets     string
+	pushTemplateDir string
+	// profiles are all profiles of the profiles file
+	profiles []profile.Profile
+	// profile is the selected profile or nil if none is used
+	profile *profile.Profile
+	// tlsOptions are the TLS settings besides the profiles, they are merged when switching profiles
+	tlsOptions profile.TLSOptions
 }
 
-func loadConfig() config {
+func loadConfig() (config, error) {
 	var cfg config
-	flag.StringVar(&cfg.url, "url", "http://localhost:8080", "URL of the SIRI endpoint")
-	flag.StringVar(&cfg.clientRef, "clientref", "client", "Client Reference to use in requests")
-	flag.StringVar(&cfg.clientPort, "port", ":8000", "Port where the client is listening for incoming requests")
+	registerClientFlags(flag.CommandLine, &cfg)
+	registerListenerFlags(flag.CommandLine, &cfg)
+	flag.BoolVar(
+		&cfg.fetched,
+		"fetched",
+		false,
+		"Automatically send a DataSupplyRequest after a DataReadyNotification was acknowledged",
+	)
+	flag.StringVar(&cfg.historyFile, "history", "", "Exchange log of a previous session to load into the history")
 	flag.StringVar(
+		&cfg.pushTargets,
+		"pushtargets",
+		"",
+		"Comma separated consumer endpoints like consumer=http://localhost:9000 to push producer messages to",
+	)
+	flag.StringVar(
+		&cfg.pushTemplateDir,
+		"pushtemplates",
+		"templates/siri/push",
+		"Folder where the producer to consumer templates for the push targets are stored",
+	)
+
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	err := applyConfig(flag.CommandLine, &cfg)
+	return cfg, err
+}
+
+// registerCommonFlags adds the flags for the config file and the log files used by all commands
+func registerCommonFlags(flags *flag.FlagSet, cfg *config) {
+	flags.StringVar(
+		&cfg.configFile,
+		"config",
+		"",
+		"YAML config file, defaults to sirigo/config.yaml in the user config folder",
+	)
+	flags.StringVar(&cfg.logFile, "log", "sirigo.log", "Location of the log file")
+	flags.StringVar(&cfg.httpLogFile, "httplog", "sirigo.http.log", "Location of the http request response log file")
+}
+
+// registerClientFlags adds the flags needed to send requests to a SIRI server
+func registerClientFlags(flags *flag.FlagSet, cfg *config) {
+	registerCommonFlags(flags, cfg)
+	flags.StringVar(&cfg.url, "url", "http://localhost:8080", "URL of the SIRI endpoint")
+	flags.StringVar(&cfg.clientRef, "clientref", "client", "Client Reference to use in requests")
+	flags.StringVar(
 		&cfg.templateDir,
 		"templates",
 		"templates/siri/request",
 		"Folder where SIRI request templates are stored",
 	)
-	flag.StringVar(
+	flags.StringVar(&cfg.protocol, "protocol", string(siri.ProtocolSIRI), "Protocol of the server: siri or vdv453")
+	flags.StringVar(
+		&cfg.exchangeLogFile,
+		"exchangelog",
+		"",
+		"Location of the structured JSON Lines log of all exchanges, disabled if empty",
+	)
+	flags.StringVar(&cfg.schemaDir, "schemas", "", "Folder with XSD files to validate sent and received bodies")
+	flags.BoolVar(&cfg.strict, "strict", false, "Do not send requests which are not valid against the XSD files")
+	flags.StringVar(&cfg.profilesFile, "profiles", "", "YAML file with named server profiles")
+	flags.StringVar(&cfg.profileName, "profile", "", "Name of the profile to use, the first one if not set")
+	flags.DurationVar(
+		&cfg.timeout,
+		"timeout",
+		httputils.DefaultTimeout,
+		"Time a request including reading the response may take, 0 for no timeout",
+	)
+	flags.IntVar(&cfg.retry.Retries, "retries", 0, "Number of retries after connection errors and 5xx responses")
+	flags.DurationVar(
+		&cfg.retry.Backoff,
+		"backoff",
+		time.Second,
+		"Wait time before the first retry, doubled for each retry",
+	)
+	flags.BoolVar(&cfg.gzip, "gzip", false, "Compress request bodies with gzip")
+	flags.StringVar(&cfg.clientTLS.CAFile, "cacert", "", "PEM bundle of the CAs to trust for HTTPS servers")
+	flags.StringVar(&cfg.clientTLS.CertFile, "cert", "", "PEM client certificate for mutual TLS")
+	flags.StringVar(&cfg.clientTLS.KeyFile, "key", "", "PEM key of the client certificate")
+	flags.BoolVar(
+		&cfg.clientTLS.InsecureSkipVerify,
+		"insecure",
+		false,
+		"Do not verify the certificate of HTTPS servers, only for test setups",
+	)
+}
+
+// registerListenerFlags adds the flags needed to listen for SIRI server requests
+func registerListenerFlags(flags *flag.FlagSet, cfg *config) {
+	flags.StringVar(&cfg.clientPort, "port", ":8000", "Port where the client is listening for incoming requests")
+	flags.StringVar(
+		&cfg.consumerAddress,
+		"consumeraddress",
+		"",
+		"URL of the listener the server sends its requests to, derived from the port if empty",
+	)
+	flags.StringVar(
 		&cfg.autoresponseDir,
 		"autoresponse",
 		"templates/siri/autoresponse",
 		"Folder where SIRI autoresponse templates are stored",
 	)
-	flag.StringVar(&cfg.logFile, "log", "sirigo.log", "Location of the log file")
-	flag.StringVar(&cfg.httpLogFile, "httplog", "sirigo.http.log", "Location of the http request response log file")
+	flags.DurationVar(
+		&cfg.listenerTimeout,
+		"listentimeout",
+		httputils.DefaultReadHeaderTimeout,
+		"Time the server may take to send the headers of its requests",
+	)
+	flags.StringVar(&cfg.listenerTLS.CertFile, "listencert", "", "PEM server certificate to listen for HTTPS requests")
+	flags.StringVar(&cfg.listenerTLS.KeyFile, "listenkey", "", "PEM key of the server certificate")
+	flags.StringVar(
+		&cfg.listenerTLS.ClientCAFile,
+		"listenclientca",
+		"",
+		"PEM bundle of the CAs to verify client certificates of server requests, not verified if empty",
+	)
+}
 
-	flag.Parse()
+// applyConfig layers the config file and the environment variables below the parsed command line flags
+// and applies the selected profile. The profile overrides the config file, but not the environment or flags.
+func applyConfig(flags *flag.FlagSet, cfg *config) error {
+	explicit := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	fileValues, err := readConfigFile(cmp.Or(cfg.configFile, os.Getenv(envPrefix+"CONFIG")))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for name, value := range fileValues {
+		// options of other commands are ignored, so one config file can be used for all commands
+		if flags.Lookup(name) != nil && !explicit[name] {
+			errs = append(errs, setFlag(flags, name, value, "config file"))
+		}
+	}
+	flags.VisitAll(func(f *flag.Flag) {
+		key := envPrefix + strings.ToUpper(f.Name)
+		if value, ok := os.LookupEnv(key); ok && !explicit[f.Name] {
+			errs = append(errs, setFlag(flags, f.Name, value, key))
+			explicit[f.Name] = true
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	return applyProfile(cfg, explicit)
+}
+
+func setFlag(flags *flag.FlagSet, name string, value string, source string) error {
+	if err := flags.Set(name, value); err != nil {
+		return fmt.Errorf("invalid value %q for %s in %s: %w", value, name, source, err)
+	}
+	return nil
+}
+
+// readConfigFile reads the options of the config file.
+// Without a file the default file in the user config folder is used if it exists.
+func readConfigFile(file string) (map[string]string, error) {
+	if file == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, nil
+		}
+		file = filepath.Join(configDir, "sirigo", "config.yaml")
+		if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+	}
+	content, err := os.ReadFile(file) //nolint gosec // the config file is chosen by the user
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]any
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return nil, fmt.Errorf("could not parse config file %s: %w", file, err)
+	}
+	options := make(map[string]string, len(values))
+	for name, value := range values {
+		switch value.(type) {
+		case map[string]any, []any:
+			return nil, fmt.Errorf("option %s in config file %s must be a single value", name, file)
+		case nil:
+			options[name] = ""
+		default:
+			options[name] = fmt.Sprint(value)
+		}
+	}
+	return options, nil
+}
+
+// applyProfile loads the profiles file and uses the settings of the selected profile.
+// Options which were set explicitly take precedence over the profile.
+func applyProfile(cfg *config, explicit map[string]bool) error {
+	if cfg.profilesFile == "" {
+		if cfg.profileName != "" {
+			return fmt.Errorf("profile %q selected without a profiles file", cfg.profileName)
+		}
+		return nil
+	}
+	profiles, err := profile.Load(cfg.profilesFile)
+	if err != nil {
+		return err
+	}
+	selected := profiles[0]
+	if cfg.profileName != "" {
+		if selected, err = profile.Find(profiles, cfg.profileName); err != nil {
+			return err
+		}
+	}
+	cfg.profiles = profiles
+	cfg.tlsOptions = profile.TLSOptions{
+		Base: cfg.clientTLS,
+		Explicit: profile.ExplicitTLS{
+			CA:                 explicit["cacert"],
+			Cert:               explicit["cert"],
+			Key:                explicit["key"],
+			InsecureSkipVerify: explicit["insecure"],
+		},
+	}
+	cfg.profile = &selected
 
-	return cfg
+	for name, value := range map[string]struct {
+		target  *string
+		profile string
+	}{
+		"url":          {&cfg.url, selected.URL},
+		"clientref":    {&cfg.clientRef, selected.ClientRef},
+		"port":         {&cfg.clientPort, selected.Port},
+		"templates":    {&cfg.templateDir, selected.Templates},
+		"autoresponse": {&cfg.autoresponseDir, selected.Autoresponse},
+	} {
+		if value.profile != "" && !explicit[name] {
+			*value.target = value.profile
+		}
+	}
+	cfg.clientTLS = selected.TLSConfig(cfg.tlsOptions)
+	return nil
 }
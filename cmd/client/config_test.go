@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+	return file
+}
+
+func parseTestConfig(t *testing.T, args ...string) (config, error) {
+	t.Helper()
+	var cfg config
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	registerClientFlags(flags, &cfg)
+	registerListenerFlags(flags, &cfg)
+	require.NoError(t, flags.Parse(args))
+	err := applyConfig(flags, &cfg)
+	return cfg, err
+}
+
+func Test_config_file_is_overridden_by_environment_and_flags(t *testing.T) {
+	// Given
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	configFile := writeFile(t, "config.yaml", `
+url: https://file.example.com
+clientref: file
+port: ":9000"
+strict: true
+history: ignored.jsonl
+`)
+	t.Setenv("SIRIGO_CLIENTREF", "env")
+	t.Setenv("SIRIGO_PORT", ":9001")
+
+	// When
+	cfg, err := parseTestConfig(t, "-config", configFile, "-port", ":9002")
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, "https://file.example.com", cfg.url)
+	assert.Equal(t, "env", cfg.clientRef)
+	assert.Equal(t, ":9002", cfg.clientPort)
+	assert.True(t, cfg.strict)
+	assert.Equal(t, "templates/siri/request", cfg.templateDir)
+}
+
+func Test_default_config_file_is_read_from_user_config_folder(t *testing.T) {
+	// Given
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	require.NoError(t, os.Mkdir(filepath.Join(configHome, "sirigo"), 0o750))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(configHome, "sirigo", "config.yaml"),
+		[]byte("url: https://default.example.com"),
+		0o600,
+	))
+
+	// When
+	cfg, err := parseTestConfig(t)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, "https://default.example.com", cfg.url)
+}
+
+func Test_profile_overrides_config_file_but_not_environment(t *testing.T) {
+	// Given
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	profiles := writeFile(t, "profiles.yaml", `
+profiles:
+  - name: partner
+    url: https://partner.example.com
+    clientRef: partner
+    templates: partner/templates
+`)
+	configFile := writeFile(t, "config.yaml", "profiles: "+profiles+"\nurl: https://file.example.com\nprofile: partner")
+	t.Setenv("SIRIGO_TEMPLATES", "env/templates")
+
+	// When
+	cfg, err := parseTestConfig(t, "-config", configFile)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, "https://partner.example.com", cfg.url)
+	assert.Equal(t, "partner", cfg.clientRef)
+	assert.Equal(t, "env/templates", cfg.templateDir)
+	require.NotNil(t, cfg.profile)
+	assert.Equal(t, "partner", cfg.profile.Name)
+}
+
+func Test_invalid_config_is_an_error(t *testing.T) {
+	testCases := map[string]struct {
+		content  string
+		expected string
+	}{
+		"invalid value": {"strict: maybe", `invalid value "maybe" for strict in config file`},
+		"nested value":  {"url:\n  dev: http://dev", "must be a single value"},
+		"no yaml":       {"url: [", "could not parse config file"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			configFile := writeFile(t, "config.yaml", tc.content)
+
+			// When
+			_, err := parseTestConfig(t, "-config", configFile)
+
+			// Then
+			assert.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
+
+func Test_missing_config_file_is_an_error(t *testing.T) {
+	// When
+	_, err := parseTestConfig(t, "-config", filepath.Join(t.TempDir(), "missing.yaml"))
+
+	// Then
+	assert.Error(t, err)
+}
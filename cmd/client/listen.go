@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/mszalbach/sirigo/internal/siri"
+)
+
+// runListen starts only the listener for SIRI server requests and dumps every request without starting the TUI
+func runListen(args []string) int {
+	var cfg config
+	var responseTemplate string
+	var outDir string
+	flags := flag.NewFlagSet("listen", flag.ContinueOnError)
+	registerClientFlags(flags, &cfg)
+	registerListenerFlags(flags, &cfg)
+	flags.StringVar(
+		&responseTemplate,
+		"response",
+		"dataReady_response.xml",
+		"Default autoresponse template, relative to the autoresponse folder",
+	)
+	flags.StringVar(&outDir, "out", "", "Folder where every request is written to a file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if err := applyConfig(flags, &cfg); err != nil {
+		fmt.Fprintln(os.Stderr, "listen:", err)
+		return exitError
+	}
+
+	logs, closeLogs, err := openLogs(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "listen:", err)
+		return exitError
+	}
+	defer closeLogs()
+
+	siriClient, err := newClient(cfg, cfg.clientPort, logs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "listen:", err)
+		return exitError
+	}
+	if err := configureAutoResponses(&siriClient, cfg.autoresponseDir, responseTemplate); err != nil {
+		fmt.Fprintln(os.Stderr, "listen:", err)
+		return exitError
+	}
+	if outDir != "" {
+		if err := os.MkdirAll(outDir, 0o750); err != nil {
+			fmt.Fprintln(os.Stderr, "listen:", err)
+			return exitError
+		}
+	}
+
+	stopContext, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- siriClient.ListenAndServe()
+	}()
+	go dumpServerRequests(siriClient.ServerRequest, outDir)
+
+	fmt.Fprintln(os.Stderr, "listen: waiting for requests on", cfg.clientPort)
+	select {
+	case err := <-serverErr:
+		fmt.Fprintln(os.Stderr, "listen:", err)
+		return exitError
+	case <-stopContext.Done():
+	}
+
+	timeoutCtx, timeoutFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer timeoutFunc()
+	if err := siriClient.Stop(timeoutCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Warn("server stop failed", slog.Any("error", err))
+	}
+	return exitOK
+}
+
+// configureAutoResponses sets the default autoresponse and loads the rules of the autoresponse folder
+func configureAutoResponses(siriClient *siri.Client, autoresponseDir string, responseTemplate string) error {
+	templates, err := siri.NewTemplateCache(autoresponseDir)
+	if err != nil {
+		return err
+	}
+	if responseTemplate != "" {
+		body, err := templates.GetTemplate(responseTemplate)
+		if err != nil {
+			return err
+		}
+		siriClient.AutoClientResponse.Body = body
+	}
+	rules, err := siri.LoadAutoResponseRules(templates)
+	if err != nil {
+		return err
+	}
+	siriClient.AutoResponseRules.SetRules(rules)
+	return nil
+}
+
+func dumpServerRequests(serverRequests <-chan siri.ServerRequest, outDir string) {
+	count := 0
+	for request := range serverRequests {
+		count++
+		printViolations("listen", "server request", request.Violations)
+		if outDir == "" {
+			writeServerRequest(os.Stdout, request)
+			continue
+		}
+
+		name := fmt.Sprintf("%s-%04d.txt", request.ReceivedAt.UTC().Format("20060102T150405.000Z"), count)
+		file, err := os.Create(filepath.Join(outDir, name)) //nolint gosec // the folder is configured by the user
+		if err != nil {
+			slog.Error("Could not write server request", slog.String("file", name), slog.Any("error", err))
+			continue
+		}
+		writeServerRequest(file, request)
+		file.Close()
+	}
+}
+
+func writeServerRequest(w io.Writer, request siri.ServerRequest) {
+	fmt.Fprintf(
+		w,
+		"### %s %s %s %s\n",
+		request.ReceivedAt.Format(time.RFC3339Nano),
+		request.RemoteAddress,
+		request.Method,
+		request.URL,
+	)
+	names := make([]string, 0, len(request.Header))
+	for name := range request.Header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		for _, value := range request.Header[name] {
+			fmt.Fprintf(w, "%s: %s\n", name, value)
+		}
+	}
+	fmt.Fprintf(w, "\n%s\n\n", request.Body)
+}
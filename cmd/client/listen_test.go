@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mszalbach/sirigo/internal/siri"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_writes_server_requests(t *testing.T) {
+	receivedAt := time.Date(2026, 10, 18, 12, 30, 15, 123000000, time.UTC)
+	testCases := map[string]struct {
+		request  siri.ServerRequest
+		expected string
+	}{
+		"data ready notification": {
+			siri.ServerRequest{
+				RemoteAddress: "127.0.0.1:4711",
+				Method:        http.MethodPost,
+				URL:           "/siri/et",
+				Header:        http.Header{"Content-Type": {"application/xml"}, "Accept": {"text/xml", "application/xml"}},
+				Body:          "<Siri><DataReadyNotification/></Siri>",
+				ReceivedAt:    receivedAt,
+			},
+			"### 2026-10-18T12:30:15.123Z 127.0.0.1:4711 POST /siri/et\n" +
+				"Accept: text/xml\n" +
+				"Accept: application/xml\n" +
+				"Content-Type: application/xml\n" +
+				"\n<Siri><DataReadyNotification/></Siri>\n\n",
+		},
+		"request without header and body": {
+			siri.ServerRequest{
+				RemoteAddress: "127.0.0.1:4711",
+				Method:        http.MethodGet,
+				URL:           "/status",
+				ReceivedAt:    receivedAt,
+			},
+			"### 2026-10-18T12:30:15.123Z 127.0.0.1:4711 GET /status\n\n\n\n",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			var out bytes.Buffer
+
+			// When
+			writeServerRequest(&out, tc.request)
+
+			// Then
+			assert.Equal(t, tc.expected, out.String())
+		})
+	}
+}
@@ -9,34 +9,165 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/mszalbach/sirigo/internal/siri"
 	"github.com/mszalbach/sirigo/internal/ui"
+	"github.com/mszalbach/sirigo/internal/xsd"
 )
 
 func main() {
-	cfg := loadConfig()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "send":
+			os.Exit(runSend(os.Args[2:]))
+		case "listen":
+			os.Exit(runListen(os.Args[2:]))
+		case "run":
+			os.Exit(runScenarios(os.Args[2:]))
+		case "server":
+			os.Exit(runServer(os.Args[2:]))
+		}
+	}
+	runTUI()
+}
+
+// logFiles are the files the SIRI client writes its requests and responses into
+type logFiles struct {
+	http *os.File
+	// exchanges is nil if the exchange log is disabled
+	exchanges *os.File
+}
+
+// openLogs sets the default logger to write into the log file and opens the http and exchange log files
+func openLogs(cfg config) (logFiles, func(), error) {
 	logFile, err := os.OpenFile(cfg.logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return logFiles{}, nil, err
+	}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(logFile, nil)))
+
+	httpLogFile, err := os.OpenFile(cfg.httpLogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		logFile.Close()
+		return logFiles{}, nil, err
+	}
+	logs := logFiles{http: httpLogFile}
+	if cfg.exchangeLogFile != "" {
+		logs.exchanges, err = os.OpenFile(cfg.exchangeLogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			httpLogFile.Close()
+			logFile.Close()
+			return logFiles{}, nil, err
+		}
+	}
+	return logs, func() {
+		if logs.exchanges != nil {
+			logs.exchanges.Close()
+		}
+		httpLogFile.Close()
+		logFile.Close()
+	}, nil
+}
+
+// newClient creates a SIRI client listening on address which writes into the log files
+func newClient(cfg config, address string, logs logFiles) (siri.Client, error) {
+	siriClient := siri.NewClient(cfg.clientRef, cfg.url, address, logs.http)
+	switch protocol := siri.Protocol(cfg.protocol); protocol {
+	case siri.ProtocolSIRI, siri.ProtocolVDV453:
+		siriClient.Protocol = protocol
+	default:
+		return siri.Client{}, fmt.Errorf("unknown protocol %q, use siri or vdv453", cfg.protocol)
+	}
+	if cfg.profile != nil {
+		siriClient.Header = cfg.profile.Header()
+		auth, err := cfg.profile.Auth.Authenticator()
+		if err != nil {
+			return siri.Client{}, err
+		}
+		siriClient.SetAuthenticator(auth)
+	}
+	siriClient.SetTimeout(cfg.timeout)
+	siriClient.SetRetryPolicy(cfg.retry)
+	siriClient.SetCompressRequests(cfg.gzip)
+	siriClient.SetListenerTimeout(cfg.listenerTimeout)
+	if err := siriClient.SetTLS(cfg.clientTLS); err != nil {
+		return siri.Client{}, err
+	}
+	if !cfg.listenerTLS.IsZero() {
+		if err := siriClient.SetListenerTLS(cfg.listenerTLS); err != nil {
+			return siri.Client{}, err
+		}
+		siriClient.ConsumerAddress = strings.Replace(siriClient.ConsumerAddress, "http://", "https://", 1)
+	}
+	if cfg.consumerAddress != "" {
+		siriClient.ConsumerAddress = cfg.consumerAddress
+	}
+	if logs.exchanges != nil {
+		siriClient.History.LogTo(logs.exchanges)
+	}
+	if cfg.schemaDir != "" {
+		schema, err := xsd.Load(cfg.schemaDir)
+		if err != nil {
+			return siri.Client{}, err
+		}
+		siriClient.Schema = schema
+		siriClient.StrictValidation = cfg.strict
+	}
+	return siriClient, nil
+}
+
+// printViolations prints the schema violations of a body as warnings
+func printViolations(command string, subject string, violations []xsd.Violation) {
+	for _, violation := range violations {
+		fmt.Fprintf(os.Stderr, "%s: %s is not valid: %s\n", command, subject, violation)
+	}
+}
+
+// loadHistory imports the exchanges of a previous session into the history of the client
+func loadHistory(siriClient *siri.Client, file string) error {
+	f, err := os.Open(file) //nolint gosec // the exchange log is chosen by the user
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	exchanges, err := siri.ReadExchanges(f)
+	if err != nil {
+		return fmt.Errorf("could not load history %s: %w", file, err)
+	}
+	siriClient.History.Import(exchanges)
+	return nil
+}
+
+func runTUI() {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
+	logs, closeLogs, err := openLogs(cfg)
 	if err != nil {
 		panic(err)
 	}
-	logger := slog.New(slog.NewJSONHandler(logFile, nil))
-	defer logFile.Close()
-	slog.SetDefault(logger)
+	defer closeLogs()
 
 	cancelContext, cancel := context.WithCancelCause(context.Background())
 	defer cancel(nil)
 	stopContext, stop := signal.NotifyContext(cancelContext, syscall.SIGTERM, syscall.SIGINT)
 	defer stop()
 
-	httpLogFile, err := os.OpenFile(cfg.httpLogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	siriClient, err := newClient(cfg, cfg.clientPort, logs)
 	if err != nil {
 		panic(err)
 	}
-	defer httpLogFile.Close()
-	siriClient := siri.NewClient(cfg.clientRef, cfg.url, cfg.clientPort, httpLogFile)
+	siriClient.SetFetchedMode(cfg.fetched)
+	if cfg.historyFile != "" {
+		if err := loadHistory(&siriClient, cfg.historyFile); err != nil {
+			panic(err)
+		}
+	}
 
 	clientTemplates, err := siri.NewTemplateCache(cfg.templateDir)
 	if err != nil {
@@ -47,7 +178,25 @@ func main() {
 		panic(err)
 	}
 
-	app := ui.NewSiriApp(&siriClient, clientTemplates, serverTemplates, cancel)
+	var pushTemplates siri.TemplateCache
+	if siriClient.PushTargets, err = siri.ParsePushTargets(cfg.pushTargets); err != nil {
+		panic(err)
+	}
+	if len(siriClient.PushTargets) > 0 {
+		if pushTemplates, err = siri.NewTemplateCache(cfg.pushTemplateDir); err != nil {
+			panic(err)
+		}
+	}
+
+	app := ui.NewSiriApp(
+		&siriClient,
+		clientTemplates,
+		serverTemplates,
+		pushTemplates,
+		cfg.profiles,
+		cfg.tlsOptions,
+		cancel,
+	)
 
 	go func() {
 		if err := app.Run(); err != nil {
@@ -55,6 +204,7 @@ func main() {
 			cancel(err)
 		}
 	}()
+	go siriClient.MonitorHeartbeats(stopContext, time.Second)
 	go func() {
 		if err := siriClient.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
 			slog.Error(
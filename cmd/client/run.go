@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mszalbach/sirigo/internal/scenario"
+	"github.com/mszalbach/sirigo/internal/siri"
+)
+
+// exitScenarioFailure is used when at least one scenario failed
+const exitScenarioFailure = 4
+
+// runScenarios runs all scenario files given as arguments and reports the results on stdout
+func runScenarios(args []string) int {
+	var cfg config
+	var responseTemplate string
+	var junitFile string
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: sirigo run [options] scenario.yaml...")
+		flags.PrintDefaults()
+	}
+	registerClientFlags(flags, &cfg)
+	registerListenerFlags(flags, &cfg)
+	flags.StringVar(
+		&responseTemplate,
+		"response",
+		"dataReady_response.xml",
+		"Default autoresponse template, relative to the autoresponse folder",
+	)
+	flags.StringVar(&junitFile, "junit", "", "Write a JUnit XML report to this file")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if err := applyConfig(flags, &cfg); err != nil {
+		fmt.Fprintln(os.Stderr, "run:", err)
+		return exitError
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "run: at least one scenario file is required")
+		flags.Usage()
+		return exitError
+	}
+
+	scenarios := make([]scenario.Scenario, 0, flags.NArg())
+	for _, file := range flags.Args() {
+		s, err := scenario.Load(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "run:", err)
+			return exitError
+		}
+		scenarios = append(scenarios, s)
+	}
+
+	logs, closeLogs, err := openLogs(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "run:", err)
+		return exitError
+	}
+	defer closeLogs()
+
+	siriClient, err := newClient(cfg, cfg.clientPort, logs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "run:", err)
+		return exitError
+	}
+	if err := configureAutoResponses(&siriClient, cfg.autoresponseDir, responseTemplate); err != nil {
+		fmt.Fprintln(os.Stderr, "run:", err)
+		return exitError
+	}
+	templates, err := siri.NewTemplateCache(cfg.templateDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "run:", err)
+		return exitError
+	}
+
+	stopContext, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	go func() {
+		if err := siriClient.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("SIRI client could not be started", slog.String("address", cfg.clientPort), slog.Any("error", err))
+			fmt.Fprintln(os.Stderr, "run: listener could not be started:", err)
+		}
+	}()
+	defer func() {
+		timeoutCtx, timeoutFunc := context.WithTimeout(context.Background(), 10*time.Second)
+		defer timeoutFunc()
+		if err := siriClient.Stop(timeoutCtx); err != nil {
+			slog.Warn("server stop failed", slog.Any("error", err))
+		}
+	}()
+
+	runner := scenario.NewRunner(&siriClient, templates)
+	results := make([]scenario.Result, 0, len(scenarios))
+	for _, s := range scenarios {
+		results = append(results, runner.Run(stopContext, s))
+	}
+
+	scenario.WriteReport(os.Stdout, results)
+	if junitFile != "" {
+		if err := writeJUnitFile(junitFile, results); err != nil {
+			fmt.Fprintln(os.Stderr, "run:", err)
+			return exitError
+		}
+	}
+
+	for _, result := range results {
+		if !result.Passed() {
+			return exitScenarioFailure
+		}
+	}
+	return exitOK
+}
+
+func writeJUnitFile(file string, results []scenario.Result) error {
+	f, err := os.Create(file) //nolint gosec // the report file is chosen by the user
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return scenario.WriteJUnit(f, results)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/mszalbach/sirigo/internal/siri"
+)
+
+// exit codes of the headless commands
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitHTTPFailure = 2
+	exitSIRIFailure = 3
+)
+
+// runSend sends a single template to the SIRI server without starting the TUI.
+// The response body is written to stdout and the exit code reflects the HTTP and SIRI status.
+func runSend(args []string) int {
+	var cfg config
+	var templateName string
+	flags := flag.NewFlagSet("send", flag.ContinueOnError)
+	registerClientFlags(flags, &cfg)
+	flags.StringVar(&templateName, "template", "", "Template to send, relative to the templates folder")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if err := applyConfig(flags, &cfg); err != nil {
+		fmt.Fprintln(os.Stderr, "send:", err)
+		return exitError
+	}
+	if templateName == "" {
+		fmt.Fprintln(os.Stderr, "send: -template is required")
+		flags.Usage()
+		return exitError
+	}
+
+	logs, closeLogs, err := openLogs(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "send:", err)
+		return exitError
+	}
+	defer closeLogs()
+
+	// Ctrl-C aborts the request
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	response, err := send(ctx, cfg, templateName, logs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "send:", err)
+		return exitError
+	}
+	printViolations("send", "request", response.RequestViolations)
+	printViolations("send", "response", response.Violations)
+	fmt.Fprintln(os.Stdout, response.Body)
+	return exitCode(response)
+}
+
+func send(ctx context.Context, cfg config, templateName string, logs logFiles) (siri.ServerResponse, error) {
+	templates, err := siri.NewTemplateCache(cfg.templateDir)
+	if err != nil {
+		return siri.ServerResponse{}, err
+	}
+	requestTemplate, err := templates.GetTemplate(templateName)
+	if err != nil {
+		return siri.ServerResponse{}, err
+	}
+
+	// the client does not listen, so no address is needed
+	siriClient, err := newClient(cfg, "", logs)
+	if err != nil {
+		return siri.ServerResponse{}, err
+	}
+	return siriClient.Send(ctx, siri.ClientRequest{
+		URL:    siriClient.TemplateURL(templateName, requestTemplate),
+		Body:   requestTemplate,
+		Header: siri.GetHeadersFromTemplate(requestTemplate),
+	})
+}
+
+// exitCode reports failures of the HTTP status before failures reported within the SIRI message
+func exitCode(response siri.ServerResponse) int {
+	if response.Status < 200 || response.Status > 299 {
+		fmt.Fprintf(os.Stderr, "send: HTTP status %d\n", response.Status)
+		return exitHTTPFailure
+	}
+	if status, found := response.MessageStatus(); found && !status {
+		fmt.Fprintf(os.Stderr, "send: %s reported status false\n", response.MessageName())
+		return exitSIRIFailure
+	}
+	return exitOK
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mszalbach/sirigo/internal/siri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_exit_code_of_responses(t *testing.T) {
+	testCases := map[string]struct {
+		status   int
+		body     string
+		expected int
+	}{
+		"successful subscription": {
+			200,
+			"<Siri><SubscriptionResponse><ResponseStatus><Status>true</Status></ResponseStatus></SubscriptionResponse></Siri>",
+			exitOK,
+		},
+		"failed subscription": {
+			200,
+			"<Siri><SubscriptionResponse><ResponseStatus><Status>false</Status></ResponseStatus></SubscriptionResponse></Siri>",
+			exitSIRIFailure,
+		},
+		"delivery without status": {200, "<Siri><ServiceDelivery></ServiceDelivery></Siri>", exitOK},
+		"no SIRI message":         {204, "", exitOK},
+		"server error": {
+			500,
+			"<Siri><CheckStatusResponse><Status>true</Status></CheckStatusResponse></Siri>",
+			exitHTTPFailure,
+		},
+		"HTTP status before SIRI status": {
+			400,
+			"<Siri><CheckStatusResponse><Status>false</Status></CheckStatusResponse></Siri>",
+			exitHTTPFailure,
+		},
+		"redirect": {302, "", exitHTTPFailure},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			response := siri.ServerResponse{Status: tc.status, Body: tc.body}
+			if tc.body != "" {
+				message, err := siri.ParseMessage(tc.body)
+				require.NoError(t, err)
+				response.Message = message
+			}
+
+			// When
+			actual := exitCode(response)
+
+			// Then
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
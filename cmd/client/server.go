@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mszalbach/sirigo/internal/siri"
+)
+
+// runServer simulates a SIRI server for testing consumers without starting the TUI
+func runServer(args []string) int {
+	var cfg config
+	var address string
+	var producerRef string
+	var deliveryDir string
+	var mappingsDir string
+	var dataReadyInterval time.Duration
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	registerCommonFlags(flags, &cfg)
+	flags.StringVar(&address, "serverport", ":8080", "Port where the server is listening for consumer requests")
+	flags.StringVar(&producerRef, "producerref", "sirigo", "Producer Reference to use in responses and notifications")
+	flags.StringVar(
+		&deliveryDir,
+		"deliveries",
+		"templates/siri/delivery",
+		"Folder with the delivery templates per service like et.xml",
+	)
+	flags.StringVar(
+		&mappingsDir,
+		"mappings",
+		"",
+		"Wiremock folder with mappings and __files which answer matching requests instead of the simulation",
+	)
+	flags.DurationVar(
+		&dataReadyInterval,
+		"dataready",
+		30*time.Second,
+		"Time between two DataReadyNotifications sent to the subscribers, 0 disables them",
+	)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if err := applyConfig(flags, &cfg); err != nil {
+		fmt.Fprintln(os.Stderr, "server:", err)
+		return exitError
+	}
+
+	logs, closeLogs, err := openLogs(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "server:", err)
+		return exitError
+	}
+	defer closeLogs()
+
+	deliveries, err := siri.NewTemplateCache(deliveryDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "server:", err)
+		return exitError
+	}
+	server := siri.NewServer(producerRef, address, deliveries, logs.http)
+	if mappingsDir != "" {
+		mappings, err := siri.LoadMappings(mappingsDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "server:", err)
+			return exitError
+		}
+		server.SetMappings(mappings)
+	}
+
+	stopContext, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+	if dataReadyInterval > 0 {
+		go server.RunDataReady(stopContext, dataReadyInterval)
+	}
+
+	fmt.Fprintln(os.Stderr, "server: waiting for requests on", address)
+	select {
+	case err := <-serverErr:
+		fmt.Fprintln(os.Stderr, "server:", err)
+		return exitError
+	case <-stopContext.Done():
+	}
+
+	timeoutCtx, timeoutFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer timeoutFunc()
+	if err := server.Stop(timeoutCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Warn("server stop failed", slog.Any("error", err))
+	}
+	return exitOK
+}
@@ -0,0 +1,216 @@
+package httputils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HeaderAuthorization is the HTTP header key for credentials
+const HeaderAuthorization = "Authorization"
+
+// Redacted replaces credentials in logged headers
+const Redacted = "[redacted]"
+
+// HeaderAPIKey is the HTTP header key commonly used for API keys
+const HeaderAPIKey = "X-Api-Key"
+
+// tokenExpiryMargin refreshes OAuth2 tokens shortly before they expire
+const tokenExpiryMargin = 30 * time.Second
+
+// credentialHeaders are always redacted, even if they were not added by an Authenticator
+var credentialHeaders = []string{HeaderAuthorization, "Proxy-Authorization", HeaderAPIKey, "Api-Key"}
+
+// Authenticator adds credentials to outgoing requests
+type Authenticator interface {
+	// Authenticate adds the credentials to the request
+	Authenticate(req *http.Request) error
+	// Header returns the name of the header containing the credentials, it is redacted in logs
+	Header() string
+}
+
+// transportAuthenticator is an Authenticator which sends requests of its own, like fetching tokens.
+// It uses the transport of the client, so the same CAs and client certificate are used.
+type transportAuthenticator interface {
+	authenticateWith(transport http.RoundTripper, req *http.Request) error
+}
+
+// BasicAuth authenticates with username and password
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Authenticate adds the Basic Authorization header
+func (a BasicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// Header returns the Authorization header
+func (a BasicAuth) Header() string {
+	return HeaderAuthorization
+}
+
+// BearerToken authenticates with a static token
+type BearerToken struct {
+	Token string
+}
+
+// Authenticate adds the Bearer Authorization header
+func (a BearerToken) Authenticate(req *http.Request) error {
+	req.Header.Set(HeaderAuthorization, "Bearer "+a.Token)
+	return nil
+}
+
+// Header returns the Authorization header
+func (a BearerToken) Header() string {
+	return HeaderAuthorization
+}
+
+// APIKey authenticates with a static key in a header like X-API-Key
+type APIKey struct {
+	Name  string
+	Value string
+}
+
+// Authenticate adds the key header
+func (a APIKey) Authenticate(req *http.Request) error {
+	req.Header.Set(a.Name, a.Value)
+	return nil
+}
+
+// Header returns the name of the key header
+func (a APIKey) Header() string {
+	return a.Name
+}
+
+// OAuth2ClientCredentials fetches a token with the OAuth2 client credentials grant and refreshes it when it expires
+type OAuth2ClientCredentials struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+	mu           sync.Mutex
+	token        string
+	expiry       time.Time
+}
+
+// NewOAuth2ClientCredentials creates an Authenticator fetching its tokens from the token endpoint
+func NewOAuth2ClientCredentials(
+	tokenURL string,
+	clientID string,
+	clientSecret string,
+	scopes []string,
+) *OAuth2ClientCredentials {
+	return &OAuth2ClientCredentials{
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		scopes:       scopes,
+	}
+}
+
+// Authenticate adds the Bearer Authorization header with a valid token
+func (a *OAuth2ClientCredentials) Authenticate(req *http.Request) error {
+	return a.authenticateWith(nil, req)
+}
+
+// authenticateWith fetches a missing or expired token with the transport and the context of the request
+func (a *OAuth2ClientCredentials) authenticateWith(transport http.RoundTripper, req *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token == "" || time.Now().After(a.expiry) {
+		if err := a.fetchToken(req.Context(), transport); err != nil {
+			return err
+		}
+	}
+	req.Header.Set(HeaderAuthorization, "Bearer "+a.token)
+	return nil
+}
+
+// Header returns the Authorization header
+func (a *OAuth2ClientCredentials) Header() string {
+	return HeaderAuthorization
+}
+
+// Invalidate forces a new token for the next request, for example after the server rejected the current one
+func (a *OAuth2ClientCredentials) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = ""
+}
+
+// tokenResponse is the successful response of the token endpoint
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// fetchToken requests a new token, a nil transport uses the default transport
+func (a *OAuth2ClientCredentials) fetchToken(ctx context.Context, transport http.RoundTripper) error {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.scopes) > 0 {
+		form.Set("scope", strings.Join(a.scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(HeaderContentType, "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(a.clientID), url.QueryEscape(a.clientSecret))
+
+	client := http.Client{Timeout: DefaultTimeout, Transport: transport}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not fetch OAuth2 token: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("could not fetch OAuth2 token: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not fetch OAuth2 token: %s: %s", res.Status, body)
+	}
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return fmt.Errorf("could not parse OAuth2 token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return errors.New("no access_token in OAuth2 token response")
+	}
+	a.token = token.AccessToken
+	a.expiry = tokenExpiry(time.Now(), token.ExpiresIn)
+	return nil
+}
+
+// tokenExpiry returns when a token valid for expiresIn seconds is refreshed.
+// The margin is at most half of the lifetime, so short-lived tokens are still used.
+func tokenExpiry(now time.Time, expiresIn int) time.Time {
+	if expiresIn <= 0 {
+		// tokens without expiry are used until the server rejects them
+		return now.Add(24 * time.Hour)
+	}
+	lifetime := time.Duration(expiresIn) * time.Second
+	return now.Add(lifetime - min(tokenExpiryMargin, lifetime/2))
+}
+
+// RedactHeader returns a copy of the header where the known credential headers and the names are replaced
+func RedactHeader(header http.Header, names ...string) http.Header {
+	redactedHeader := header.Clone()
+	for _, name := range append(names, credentialHeaders...) {
+		if redactedHeader.Get(name) != "" {
+			redactedHeader.Set(name, Redacted)
+		}
+	}
+	return redactedHeader
+}
@@ -0,0 +1,240 @@
+package httputils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_static_credentials_are_sent_and_redacted(t *testing.T) {
+	testCases := map[string]struct {
+		auth     Authenticator
+		header   string
+		expected string
+	}{
+		"basic":   {BasicAuth{Username: "user", Password: "secret"}, "Authorization", "Basic dXNlcjpzZWNyZXQ="},
+		"bearer":  {BearerToken{Token: "token"}, "Authorization", "Bearer token"},
+		"api key": {APIKey{Name: "X-API-Key", Value: "key"}, "X-Api-Key", "key"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, tc.expected, req.Header.Get(tc.header))
+				rw.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+			client := NewLoggingClient(io.Discard)
+			client.SetAuthenticator(tc.auth)
+
+			// When
+			response, err := client.PostXML(t.Context(), server.URL, "<Siri/>", nil)
+
+			// Then
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			assert.Equal(t, "[redacted]", response.RequestHeader.Get(tc.header))
+		})
+	}
+}
+
+// newTokenServer returns a token endpoint issuing the tokens token-1, token-2 and so on
+func newTokenServer(t *testing.T, expiresIn int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var issued atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		clientID, clientSecret, ok := req.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "sirigo", clientID)
+		assert.Equal(t, "secret", clientSecret)
+		assert.NoError(t, req.ParseForm())
+		assert.Equal(t, "client_credentials", req.PostForm.Get("grant_type"))
+		assert.Equal(t, "siri read", req.PostForm.Get("scope"))
+		rw.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(rw, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d}`, issued.Add(1), expiresIn)
+	}))
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+func Test_oauth2_token_is_fetched_once_and_reused(t *testing.T) {
+	// Given
+	tokenServer, issued := newTokenServer(t, 3600)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "Bearer token-1", req.Header.Get("Authorization"))
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := NewLoggingClient(io.Discard)
+	client.SetAuthenticator(NewOAuth2ClientCredentials(tokenServer.URL, "sirigo", "secret", []string{"siri", "read"}))
+
+	// When
+	_, firstErr := client.PostXML(t.Context(), server.URL, "<Siri/>", nil)
+	_, secondErr := client.PostXML(t.Context(), server.URL, "<Siri/>", nil)
+
+	// Then
+	require.NoError(t, firstErr)
+	require.NoError(t, secondErr)
+	assert.Equal(t, int32(1), issued.Load())
+}
+
+func Test_oauth2_token_is_refreshed(t *testing.T) {
+	testCases := map[string]struct {
+		expired  bool
+		rejected string
+	}{
+		"expired":  {expired: true},
+		"rejected": {rejected: "Bearer token-1"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			tokenServer, issued := newTokenServer(t, 3600)
+			var lastToken atomic.Value
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				body, err := io.ReadAll(req.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, "<Siri/>", string(body))
+				lastToken.Store(req.Header.Get("Authorization"))
+				if req.Header.Get("Authorization") == tc.rejected {
+					rw.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				rw.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+			auth := NewOAuth2ClientCredentials(tokenServer.URL, "sirigo", "secret", []string{"siri", "read"})
+			client := NewLoggingClient(io.Discard)
+			client.SetAuthenticator(auth)
+
+			// When
+			_, firstErr := client.PostXML(t.Context(), server.URL, "<Siri/>", nil)
+			if tc.expired {
+				auth.expiry = time.Now().Add(-time.Second)
+			}
+			response, secondErr := client.PostXML(t.Context(), server.URL, "<Siri/>", nil)
+
+			// Then
+			require.NoError(t, firstErr)
+			require.NoError(t, secondErr)
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			assert.Equal(t, int32(2), issued.Load())
+			assert.Equal(t, "Bearer token-2", lastToken.Load())
+		})
+	}
+}
+
+func Test_failed_token_request_is_an_error(t *testing.T) {
+	// Given
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer tokenServer.Close()
+	client := NewLoggingClient(io.Discard)
+	client.SetAuthenticator(NewOAuth2ClientCredentials(tokenServer.URL, "sirigo", "wrong", nil))
+
+	// When
+	_, err := client.PostXML(t.Context(), "http://localhost", "<Siri/>", nil)
+
+	// Then
+	assert.ErrorContains(t, err, "could not fetch OAuth2 token: 401 Unauthorized")
+}
+
+func Test_oauth2_token_expiry_keeps_a_margin(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		expiresIn int
+		expected  time.Time
+	}{
+		"long-lived":       {3600, now.Add(time.Hour - 30*time.Second)},
+		"within margin":    {30, now.Add(15 * time.Second)},
+		"short-lived":      {10, now.Add(5 * time.Second)},
+		"without expiry":   {0, now.Add(24 * time.Hour)},
+		"negative expiry":  {-1, now.Add(24 * time.Hour)},
+		"just over margin": {61, now.Add(31 * time.Second)},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// When
+			actual := tokenExpiry(now, tc.expiresIn)
+
+			// Then
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func Test_oauth2_token_request_uses_context_of_request(t *testing.T) {
+	// Given
+	tokenServer, issued := newTokenServer(t, 3600)
+	client := NewLoggingClient(io.Discard)
+	client.SetAuthenticator(NewOAuth2ClientCredentials(tokenServer.URL, "sirigo", "secret", []string{"siri", "read"}))
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	// When
+	_, err := client.PostXML(ctx, "http://localhost", "<Siri/>", nil)
+
+	// Then
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(0), issued.Load())
+}
+
+func Test_oauth2_token_is_fetched_with_tls_settings_of_client(t *testing.T) {
+	// Given
+	serverCert, serverKey := writeCertificate(t, "server")
+	tlsConfig, err := ServerTLSConfig{CertFile: serverCert, KeyFile: serverKey}.Config()
+	require.NoError(t, err)
+	tokenServer := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(rw, `{"access_token":"token","token_type":"Bearer","expires_in":3600}`)
+	}))
+	tokenServer.TLS = tlsConfig
+	tokenServer.StartTLS()
+	defer tokenServer.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := newTLSClient(t, TLSConfig{CAFile: serverCert})
+	client.SetAuthenticator(NewOAuth2ClientCredentials(tokenServer.URL, "sirigo", "secret", nil))
+
+	// When
+	response, err := client.PostXML(t.Context(), server.URL, "<Siri/>", nil)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func Test_credential_headers_are_redacted_without_authenticator(t *testing.T) {
+	// Given
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := NewLoggingClient(io.Discard)
+
+	// When
+	response, err := client.PostXML(t.Context(), server.URL, "<Siri/>", http.Header{
+		"Authorization": {"Bearer token"},
+		"X-Api-Key":     {"key"},
+		"X-Custom":      {"value"},
+	})
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, "[redacted]", response.RequestHeader.Get("Authorization"))
+	assert.Equal(t, "[redacted]", response.RequestHeader.Get("X-Api-Key"))
+	assert.Equal(t, "value", response.RequestHeader.Get("X-Custom"))
+}
@@ -0,0 +1,77 @@
+package httputils
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// HeaderContentEncoding is the HTTP header key for Content-Encoding
+	HeaderContentEncoding = "Content-Encoding"
+	// HeaderAcceptEncoding is the HTTP header key for Accept-Encoding
+	HeaderAcceptEncoding = "Accept-Encoding"
+	// EncodingGzip is the Content-Encoding of gzip compressed bodies
+	EncodingGzip = "gzip"
+	// EncodingDeflate is the Content-Encoding of zlib compressed bodies
+	EncodingDeflate = "deflate"
+)
+
+// acceptEncoding is sent with requests which do not set an Accept-Encoding themselves
+const acceptEncoding = EncodingGzip + ", " + EncodingDeflate
+
+// DefaultMaxDecodedSize is the maximum size of a decompressed body in bytes,
+// so a small compressed body cannot fill up the memory
+const DefaultMaxDecodedSize = 64 << 20
+
+// errBodyTooLarge is returned if a decompressed body exceeds the maximum size
+var errBodyTooLarge = errors.New("decompressed body too large")
+
+// decodeBody decompresses a gzip or deflate body of at most maxSize bytes and reports whether it was compressed.
+// Bodies with another encoding are returned unchanged.
+func decodeBody(encoding string, body []byte, maxSize int64) ([]byte, bool, error) {
+	var reader io.Reader
+	var err error
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case EncodingGzip, "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+	case EncodingDeflate:
+		reader, err = zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			// some servers send raw deflate data without the zlib header
+			reader, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+	default:
+		return body, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("could not decode %s body: %w", encoding, err)
+	}
+	// one more byte is read to tell a body of exactly maxSize bytes from a larger one
+	decoded, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("could not decode %s body: %w", encoding, err)
+	}
+	if int64(len(decoded)) > maxSize {
+		return nil, false, fmt.Errorf("could not decode %s body: %w, limit is %d bytes", encoding, errBodyTooLarge, maxSize)
+	}
+	return decoded, true, nil
+}
+
+// encodeBody compresses the body with gzip
+func encodeBody(body []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(body); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,231 @@
+package httputils
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func zlibBody(t *testing.T, body string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := zlib.NewWriter(&buffer)
+	_, err := writer.Write([]byte(body))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func rawDeflateBody(t *testing.T, body string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer, err := flate.NewWriter(&buffer, flate.DefaultCompression)
+	require.NoError(t, err)
+	_, err = writer.Write([]byte(body))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func Test_bodies_are_decoded(t *testing.T) {
+	gzipped, err := encodeBody([]byte("<Siri/>"))
+	require.NoError(t, err)
+	testCases := map[string]struct {
+		encoding        string
+		body            []byte
+		expectedDecoded bool
+	}{
+		"gzip":        {"gzip", gzipped, true},
+		"deflate":     {"deflate", zlibBody(t, "<Siri/>"), true},
+		"raw deflate": {"deflate", rawDeflateBody(t, "<Siri/>"), true},
+		"identity":    {"", []byte("<Siri/>"), false},
+		"unknown":     {"br", []byte("<Siri/>"), false},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// When
+			actual, decoded, err := decodeBody(tc.encoding, tc.body, DefaultMaxDecodedSize)
+
+			// Then
+			require.NoError(t, err)
+			assert.Equal(t, "<Siri/>", string(actual))
+			assert.Equal(t, tc.expectedDecoded, decoded)
+		})
+	}
+}
+
+func Test_invalid_compressed_body_is_an_error(t *testing.T) {
+	// When
+	_, _, err := decodeBody("gzip", []byte("<Siri/>"), DefaultMaxDecodedSize)
+
+	// Then
+	assert.ErrorContains(t, err, "could not decode gzip body")
+}
+
+func Test_decoded_bodies_are_limited(t *testing.T) {
+	gzipped, err := encodeBody([]byte(strings.Repeat("<Siri/>", 10)))
+	require.NoError(t, err)
+	testCases := map[string]struct {
+		encoding string
+		body     []byte
+	}{
+		"gzip":    {"gzip", gzipped},
+		"deflate": {"deflate", zlibBody(t, strings.Repeat("<Siri/>", 10))},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// When
+			exact, _, exactErr := decodeBody(tc.encoding, tc.body, 70)
+			_, _, err := decodeBody(tc.encoding, tc.body, 69)
+
+			// Then
+			require.NoError(t, exactErr)
+			assert.Len(t, exact, 70)
+			assert.ErrorIs(t, err, errBodyTooLarge)
+		})
+	}
+}
+
+func Test_too_large_compressed_responses_are_an_error(t *testing.T) {
+	// Given
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.Header().Set(HeaderContentEncoding, EncodingDeflate)
+		_, err := rw.Write(zlibBody(t, strings.Repeat("<Siri/>", 100)))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	client := NewLoggingClient(io.Discard)
+	client.maxDecodedSize = 100
+
+	// When
+	_, err := client.PostXML(t.Context(), server.URL, "<Siri/>", nil)
+
+	// Then
+	assert.ErrorIs(t, err, errBodyTooLarge)
+}
+
+func Test_compressed_responses_are_decoded(t *testing.T) {
+	// Given
+	body := strings.Repeat("<Siri/>", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "gzip, deflate", req.Header.Get(HeaderAcceptEncoding))
+		rw.Header().Set(HeaderContentEncoding, EncodingDeflate)
+		_, err := rw.Write(zlibBody(t, body))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+	var log bytes.Buffer
+	client := NewLoggingClient(&log)
+
+	// When
+	response, err := client.PostXML(t.Context(), server.URL, "<Siri/>", nil)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, body, response.Body)
+	assert.Regexp(t, `Content-Encoding deflate, \d+ bytes compressed, 700 bytes decompressed`, log.String())
+}
+
+func Test_request_bodies_are_compressed(t *testing.T) {
+	testCases := map[string]struct {
+		compress         bool
+		expectedEncoding string
+	}{
+		"compressed":     {true, EncodingGzip},
+		"not compressed": {false, ""},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, tc.expectedEncoding, req.Header.Get(HeaderContentEncoding))
+				body, err := io.ReadAll(req.Body)
+				assert.NoError(t, err)
+				decoded, _, err := decodeBody(req.Header.Get(HeaderContentEncoding), body, DefaultMaxDecodedSize)
+				assert.NoError(t, err)
+				assert.Equal(t, "<Siri/>", string(decoded))
+			}))
+			defer server.Close()
+			client := NewLoggingClient(io.Discard)
+			client.SetCompressRequests(tc.compress)
+
+			// When
+			response, err := client.PostXML(t.Context(), server.URL, "<Siri/>", nil)
+
+			// Then
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			assert.Equal(t, tc.expectedEncoding, response.RequestHeader.Get(HeaderContentEncoding))
+		})
+	}
+}
+
+func Test_listener_decodes_compressed_requests(t *testing.T) {
+	// Given
+	var log bytes.Buffer
+	var received string
+	handler := loggingMiddleware(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		received = string(body)
+	}), &log, DefaultMaxDecodedSize)
+	gzipped, err := encodeBody([]byte("<Siri/>"))
+	require.NoError(t, err)
+	request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(gzipped))
+	request.Header.Set(HeaderContentEncoding, EncodingGzip)
+
+	// When
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	// Then
+	assert.Equal(t, "<Siri/>", received)
+	expectedLog := fmt.Sprintf("Content-Encoding gzip, %d bytes compressed, 7 bytes decompressed", len(gzipped))
+	assert.Contains(t, log.String(), expectedLog)
+}
+
+func Test_listener_rejects_too_large_compressed_requests(t *testing.T) {
+	// Given
+	called := false
+	handler := loggingMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		called = true
+	}), io.Discard, 100)
+	gzipped, err := encodeBody([]byte(strings.Repeat("<Siri/>", 100)))
+	require.NoError(t, err)
+	request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(gzipped))
+	request.Header.Set(HeaderContentEncoding, EncodingGzip)
+	response := httptest.NewRecorder()
+
+	// When
+	handler.ServeHTTP(response, request)
+
+	// Then
+	assert.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+	assert.False(t, called)
+}
+
+func Test_listener_rejects_invalid_compressed_requests(t *testing.T) {
+	// Given
+	called := false
+	handler := loggingMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		called = true
+	}), io.Discard, DefaultMaxDecodedSize)
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("<Siri/>"))
+	request.Header.Set(HeaderContentEncoding, EncodingGzip)
+	response := httptest.NewRecorder()
+
+	// When
+	handler.ServeHTTP(response, request)
+
+	// Then
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.False(t, called)
+}
@@ -3,16 +3,42 @@ package httputils
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 )
 
+// DefaultTimeout is the time a request including reading the response may take
+const DefaultTimeout = 10 * time.Second
+
+// RetryPolicy decides how often failed requests are sent again.
+// Requests are retried on connection errors and 5xx status codes.
+type RetryPolicy struct {
+	// Retries is the number of retries after the first attempt
+	Retries int
+	// Backoff is the wait time before the first retry, it is doubled for every further retry
+	Backoff time.Duration
+}
+
+// backoff returns the wait time before the retry
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	return p.Backoff << (retry - 1)
+}
+
 // LoggingClient is a simple HTTP client wrapper which logs requests and responses
 type LoggingClient struct {
 	client http.Client
 	writer io.Writer
+	auth   Authenticator
+	retry  RetryPolicy
+	// compressRequests gzips the bodies of requests which are not encoded yet
+	compressRequests bool
+	// maxDecodedSize is the maximum size of a decompressed response body in bytes
+	maxDecodedSize int64
 }
 
 // Response represents an HTTP response
@@ -20,52 +46,183 @@ type Response struct {
 	Body       string
 	StatusCode int
 	Header     http.Header
+	// RequestHeader contains the headers which were sent with the request, credentials are redacted
+	RequestHeader http.Header
 }
 
 // NewLoggingClient creates a new LoggingClient with default settings
 func NewLoggingClient(writer io.Writer) LoggingClient {
 	return LoggingClient{
-		client: http.Client{Timeout: 10 * time.Second},
-		writer: writer,
+		client:         http.Client{Timeout: DefaultTimeout},
+		writer:         writer,
+		maxDecodedSize: DefaultMaxDecodedSize,
+	}
+}
+
+// SetTLSConfig sets the TLS configuration used for HTTPS requests, nil restores the default
+func (hc *LoggingClient) SetTLSConfig(config *tls.Config) {
+	if config == nil {
+		hc.client.Transport = nil
+		return
 	}
+	hc.client.Transport = NewTransport(config)
+}
+
+// SetTransport sets the transport used for requests, nil restores the default.
+// It allows clients to share a transport created with NewTransport.
+func (hc *LoggingClient) SetTransport(transport http.RoundTripper) {
+	hc.client.Transport = transport
+}
+
+// NewTransport creates a transport with the default settings which uses the TLS configuration for HTTPS requests
+func NewTransport(config *tls.Config) http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	return transport
 }
 
-// PostXML sends a POST request with XML content to the specified URL
-func (hc LoggingClient) PostXML(url string, body string) (Response, error) {
-	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+// SetTimeout sets the time a request including reading the response may take, 0 means no timeout
+func (hc *LoggingClient) SetTimeout(timeout time.Duration) {
+	hc.client.Timeout = timeout
+}
+
+// SetRetryPolicy sets how often failed requests are sent again
+func (hc *LoggingClient) SetRetryPolicy(policy RetryPolicy) {
+	hc.retry = policy
+}
+
+// SetAuthenticator sets how requests are authenticated, nil sends them without credentials
+func (hc *LoggingClient) SetAuthenticator(auth Authenticator) {
+	hc.auth = auth
+}
+
+// SetCompressRequests sets whether request bodies are compressed with gzip
+func (hc *LoggingClient) SetCompressRequests(compress bool) {
+	hc.compressRequests = compress
+}
+
+// PostXML sends a POST request with XML content to the specified URL.
+// The header is added to the request and may replace the Content-Type.
+func (hc LoggingClient) PostXML(ctx context.Context, url string, body string, header http.Header) (Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(body))
 	if err != nil {
 		return Response{}, err
 	}
 	req.Header.Set(HeaderContentType, ContentTypeXML)
+	for name, values := range header {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
 	return hc.Do(req)
 }
 
-// Do sends an HTTP request and returns the response
+// Do sends an HTTP request and returns the response.
+// The request is authenticated with the Authenticator and sent again once with new credentials if it was rejected.
+// Failed requests are retried according to the RetryPolicy.
+// gzip and deflate compressed responses are decompressed.
 func (hc LoggingClient) Do(req *http.Request) (Response, error) {
 	bytesBody, err := io.ReadAll(req.Body)
 	if err != nil {
 		return Response{}, err
 	}
-	// restore body because you can read only once
-	req.Body = io.NopCloser(bytes.NewBuffer(bytesBody))
+	if req.Header.Get(HeaderAcceptEncoding) == "" {
+		req.Header.Set(HeaderAcceptEncoding, acceptEncoding)
+	}
+	sentBody := bytesBody
+	requestEncodedSize := 0
+	if hc.compressRequests && len(bytesBody) > 0 && req.Header.Get(HeaderContentEncoding) == "" {
+		if sentBody, err = encodeBody(bytesBody); err != nil {
+			return Response{}, err
+		}
+		req.Header.Set(HeaderContentEncoding, EncodingGzip)
+		requestEncodedSize = len(sentBody)
+	}
 
-	res, err := hc.client.Do(req)
+	res, encodedBody, err := hc.sendWithRetries(req, sentBody)
 	if err != nil {
 		return Response{}, err
 	}
-
-	defer res.Body.Close()
-	body, err := io.ReadAll(res.Body)
+	if invalidator, ok := hc.auth.(interface{ Invalidate() }); ok && res.StatusCode == http.StatusUnauthorized {
+		invalidator.Invalidate()
+		if res, encodedBody, err = hc.sendWithRetries(req, sentBody); err != nil {
+			return Response{}, err
+		}
+	}
+	body, decoded, err := decodeBody(res.Header.Get(HeaderContentEncoding), encodedBody, hc.maxDecodedSize)
 	if err != nil {
 		return Response{}, err
 	}
+	responseEncodedSize := 0
+	if decoded {
+		responseEncodedSize = len(encodedBody)
+	}
 
-	logRequest(hc.writer, "Outgoing Request:", req, bytesBody)
-	logResponse(hc.writer, "Incoming Response:", res.StatusCode, res.Header, body)
+	logRequest(hc.writer, "Outgoing Request:", req, bytesBody, requestEncodedSize)
+	logResponse(hc.writer, "Incoming Response:", res.StatusCode, res.Header, body, responseEncodedSize)
 
+	var authHeaders []string
+	if hc.auth != nil {
+		authHeaders = append(authHeaders, hc.auth.Header())
+	}
 	return Response{
-		Body:       string(body),
-		StatusCode: res.StatusCode,
-		Header:     res.Header,
+		Body:          string(body),
+		StatusCode:    res.StatusCode,
+		Header:        res.Header,
+		RequestHeader: RedactHeader(req.Header, authHeaders...),
 	}, nil
 }
+
+// sendWithRetries sends the request until it succeeded, the retries are used up or the request was canceled
+func (hc LoggingClient) sendWithRetries(req *http.Request, body []byte) (*http.Response, []byte, error) {
+	for retry := 1; ; retry++ {
+		res, resBody, err := hc.send(req, body)
+		failed := err != nil || res.StatusCode >= http.StatusInternalServerError
+		if !failed || retry > hc.retry.Retries || req.Context().Err() != nil {
+			return res, resBody, err
+		}
+		backoff := hc.retry.backoff(retry)
+		slog.Info(
+			"Retrying failed request",
+			slog.String("url", req.URL.String()),
+			slog.Int("retry", retry),
+			slog.Duration("backoff", backoff),
+			slog.Any("error", err),
+		)
+		select {
+		case <-req.Context().Done():
+			return nil, nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// send authenticates and sends the request and reads the response body
+func (hc LoggingClient) send(req *http.Request, body []byte) (*http.Response, []byte, error) {
+	// restore body because you can read only once
+	req.Body = io.NopCloser(bytes.NewBuffer(body))
+	req.ContentLength = int64(len(body))
+	if err := hc.authenticate(req); err != nil {
+		return nil, nil, err
+	}
+
+	res, err := hc.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, resBody, nil
+}
+
+// authenticate adds the credentials of the Authenticator to the request
+func (hc LoggingClient) authenticate(req *http.Request) error {
+	if auth, ok := hc.auth.(transportAuthenticator); ok {
+		return auth.authenticateWith(hc.client.Transport, req)
+	}
+	if hc.auth != nil {
+		return hc.auth.Authenticate(req)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package httputils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFlakyServer answers with the status codes in order and with 200 afterwards
+func newFlakyServer(t *testing.T, statusCodes ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "<Siri/>", string(body))
+		request := int(requests.Add(1))
+		if request <= len(statusCodes) {
+			rw.WriteHeader(statusCodes[request-1])
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func Test_failed_requests_are_retried(t *testing.T) {
+	testCases := map[string]struct {
+		statusCodes      []int
+		retries          int
+		expectedStatus   int
+		expectedRequests int32
+	}{
+		"no retries":           {[]int{http.StatusBadGateway}, 0, http.StatusBadGateway, 1},
+		"retried until ok":     {[]int{http.StatusBadGateway, http.StatusServiceUnavailable}, 3, http.StatusOK, 3},
+		"retries used up":      {[]int{500, 500, 500, 500}, 2, http.StatusInternalServerError, 3},
+		"client errors are ok": {[]int{http.StatusBadRequest}, 3, http.StatusBadRequest, 1},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			server, requests := newFlakyServer(t, tc.statusCodes...)
+			client := NewLoggingClient(io.Discard)
+			client.SetRetryPolicy(RetryPolicy{Retries: tc.retries, Backoff: time.Millisecond})
+
+			// When
+			response, err := client.PostXML(t.Context(), server.URL, "<Siri/>", nil)
+
+			// Then
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, response.StatusCode)
+			assert.Equal(t, tc.expectedRequests, requests.Load())
+		})
+	}
+}
+
+func Test_connection_errors_are_retried(t *testing.T) {
+	// Given
+	server, _ := newFlakyServer(t)
+	url := server.URL
+	server.Close()
+	client := NewLoggingClient(io.Discard)
+	client.SetRetryPolicy(RetryPolicy{Retries: 2, Backoff: time.Millisecond})
+
+	// When
+	start := time.Now()
+	_, err := client.PostXML(t.Context(), url, "<Siri/>", nil)
+
+	// Then
+	require.Error(t, err)
+	// 1ms and 2ms backoff
+	assert.GreaterOrEqual(t, time.Since(start), 3*time.Millisecond)
+}
+
+func Test_canceled_request_is_not_retried(t *testing.T) {
+	// Given
+	server, requests := newFlakyServer(t, http.StatusServiceUnavailable)
+	client := NewLoggingClient(io.Discard)
+	client.SetRetryPolicy(RetryPolicy{Retries: 3, Backoff: time.Hour})
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	// When
+	_, err := client.PostXML(ctx, server.URL, "<Siri/>", nil)
+
+	// Then
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func Test_slow_response_times_out(t *testing.T) {
+	// Given
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := NewLoggingClient(io.Discard)
+	client.SetTimeout(20 * time.Millisecond)
+
+	// When
+	_, err := client.PostXML(t.Context(), server.URL, "<Siri/>", nil)
+
+	// Then
+	assert.ErrorContains(t, err, "Client.Timeout exceeded")
+}
+
+func Test_backoff_is_doubled(t *testing.T) {
+	// Given
+	policy := RetryPolicy{Retries: 3, Backoff: time.Second}
+
+	// Then
+	assert.Equal(t, time.Second, policy.backoff(1))
+	assert.Equal(t, 2*time.Second, policy.backoff(2))
+	assert.Equal(t, 4*time.Second, policy.backoff(3))
+}
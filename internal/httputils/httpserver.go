@@ -2,12 +2,16 @@ package httputils
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	"time"
 )
 
+// DefaultReadHeaderTimeout is the time a client may take to send the headers of its request
+const DefaultReadHeaderTimeout = 5 * time.Second
+
 // LoggingMuxServer is a simple HTTP server wrapper which logs requests and responses
 type LoggingMuxServer struct {
 	*http.Server
@@ -20,19 +24,29 @@ func NewLoggingMuxServer(address string, writer io.Writer) *LoggingMuxServer {
 	return &LoggingMuxServer{
 		Server: &http.Server{
 			Addr:              address,
-			ReadHeaderTimeout: 5 * time.Second,
-			Handler:           loggingMiddleware(mux, writer),
+			ReadHeaderTimeout: DefaultReadHeaderTimeout,
+			Handler:           loggingMiddleware(mux, writer, DefaultMaxDecodedSize),
 		},
 		mux: mux,
 	}
 }
 
+// ListenAndServe listens for HTTPS requests if a TLSConfig is set and for HTTP requests otherwise
+func (hs *LoggingMuxServer) ListenAndServe() error {
+	if hs.TLSConfig != nil {
+		// the certificates are part of the TLSConfig
+		return hs.Server.ListenAndServeTLS("", "")
+	}
+	return hs.Server.ListenAndServe()
+}
+
 // HandleFunc registers the handler function for the given pattern
 func (hs *LoggingMuxServer) HandleFunc(pattern string, handleFunc func(http.ResponseWriter, *http.Request)) {
 	hs.mux.HandleFunc(pattern, handleFunc)
 }
 
-func loggingMiddleware(next http.Handler, writer io.Writer) http.Handler {
+// loggingMiddleware logs requests and responses and decompresses request bodies of at most maxDecodedSize bytes
+func loggingMiddleware(next http.Handler, writer io.Writer, maxDecodedSize int64) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		loggingResponseWriter := &loggingResponseWriter{
 			wrappedWriter: w,
@@ -42,20 +56,39 @@ func loggingMiddleware(next http.Handler, writer io.Writer) http.Handler {
 		if err != nil {
 			slog.Warn("Could not read body from incoming server request", slog.Any("error", err))
 		}
-		// restore body because you can read only once
-		r.Body = io.NopCloser(bytes.NewBuffer(bytesBody))
+		// compressed bodies are decompressed so the handlers only see the XML
+		encodedSize := 0
+		decodedBody, decoded, err := decodeBody(r.Header.Get(HeaderContentEncoding), bytesBody, maxDecodedSize)
+		if err != nil {
+			slog.Warn("Could not decode body from incoming server request", slog.Any("error", err))
+			bytesBody = nil
+			status := http.StatusBadRequest
+			if errors.Is(err, errBodyTooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			http.Error(loggingResponseWriter, err.Error(), status)
+		} else {
+			if decoded {
+				encodedSize = len(bytesBody)
+				bytesBody = decodedBody
+				r.ContentLength = int64(len(bytesBody))
+			}
+			// restore body because you can read only once
+			r.Body = io.NopCloser(bytes.NewBuffer(bytesBody))
 
-		// call original handler
-		next.ServeHTTP(loggingResponseWriter, r)
+			// call original handler
+			next.ServeHTTP(loggingResponseWriter, r)
+		}
 
 		// log request and response
-		logRequest(writer, "Incoming Request:", r, bytesBody)
+		logRequest(writer, "Incoming Request:", r, bytesBody, encodedSize)
 		logResponse(
 			writer,
 			"Outgoing Response:",
 			loggingResponseWriter.statusCode,
 			loggingResponseWriter.Header(),
 			loggingResponseWriter.body,
+			0,
 		)
 	})
 }
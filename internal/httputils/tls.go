@@ -0,0 +1,92 @@
+package httputils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSConfig contains the files and settings needed for HTTPS connections
+type TLSConfig struct {
+	// CAFile is a PEM bundle of the certificate authorities to trust, the system pool is used if it is empty
+	CAFile string
+	// CertFile and KeyFile are the client certificate used for mutual TLS
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// IsZero reports whether nothing is configured
+func (c TLSConfig) IsZero() bool {
+	return c == TLSConfig{}
+}
+
+// ClientConfig creates the tls.Config used to connect to servers
+func (c TLSConfig) ClientConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: c.InsecureSkipVerify, //nolint gosec // only enabled on purpose for test setups
+	}
+	if c.CAFile != "" {
+		pool, err := loadCertPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	content, err := os.ReadFile(file) //nolint gosec // the CA file is chosen by the user
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("no certificates found in %s", file)
+	}
+	return pool, nil
+}
+
+// ServerTLSConfig contains the files needed to listen for HTTPS requests
+type ServerTLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is a PEM bundle used to verify client certificates, they are not requested if it is empty
+	ClientCAFile string
+}
+
+// IsZero reports whether nothing is configured
+func (c ServerTLSConfig) IsZero() bool {
+	return c == ServerTLSConfig{}
+}
+
+// Config creates the tls.Config used to accept connections
+func (c ServerTLSConfig) Config() (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load server certificate: %w", err)
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{certificate},
+	}
+	if c.ClientCAFile != "" {
+		pool, err := loadCertPool(c.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
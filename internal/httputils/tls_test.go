@@ -0,0 +1,125 @@
+package httputils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificate creates a self-signed certificate for localhost and returns the cert and key file
+func writeCertificate(t *testing.T, name string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, name+".pem")
+	keyFile := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+	return certFile, keyFile
+}
+
+// startTLSServer starts a server with the server TLS configuration
+func startTLSServer(t *testing.T, config ServerTLSConfig) *httptest.Server {
+	t.Helper()
+	tlsConfig, err := config.Config()
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = tlsConfig
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTLSClient(t *testing.T, config TLSConfig) LoggingClient {
+	t.Helper()
+	tlsConfig, err := config.ClientConfig()
+	require.NoError(t, err)
+	client := NewLoggingClient(io.Discard)
+	client.SetTLSConfig(tlsConfig)
+	return client
+}
+
+func Test_client_trusts_configured_ca(t *testing.T) {
+	// Given
+	serverCert, serverKey := writeCertificate(t, "server")
+	server := startTLSServer(t, ServerTLSConfig{CertFile: serverCert, KeyFile: serverKey})
+
+	// When
+	trusted, trustedErr := newTLSClient(t, TLSConfig{CAFile: serverCert}).PostXML(t.Context(), server.URL, "<Siri/>", nil)
+	_, untrustedErr := NewLoggingClient(io.Discard).PostXML(t.Context(), server.URL, "<Siri/>", nil)
+	insecure, insecureErr := newTLSClient(t, TLSConfig{InsecureSkipVerify: true}).
+		PostXML(t.Context(), server.URL, "<Siri/>", nil)
+
+	// Then
+	require.NoError(t, trustedErr)
+	assert.Equal(t, http.StatusOK, trusted.StatusCode)
+	assert.Error(t, untrustedErr)
+	require.NoError(t, insecureErr)
+	assert.Equal(t, http.StatusOK, insecure.StatusCode)
+}
+
+func Test_server_verifies_client_certificates(t *testing.T) {
+	// Given
+	serverCert, serverKey := writeCertificate(t, "server")
+	clientCert, clientKey := writeCertificate(t, "client")
+	otherCert, otherKey := writeCertificate(t, "other")
+	server := startTLSServer(t, ServerTLSConfig{CertFile: serverCert, KeyFile: serverKey, ClientCAFile: clientCert})
+
+	// When
+	withCert, withCertErr := newTLSClient(t, TLSConfig{CAFile: serverCert, CertFile: clientCert, KeyFile: clientKey}).
+		PostXML(t.Context(), server.URL, "<Siri/>", nil)
+	_, withoutCertErr := newTLSClient(t, TLSConfig{CAFile: serverCert}).PostXML(t.Context(), server.URL, "<Siri/>", nil)
+	_, otherCertErr := newTLSClient(t, TLSConfig{CAFile: serverCert, CertFile: otherCert, KeyFile: otherKey}).
+		PostXML(t.Context(), server.URL, "<Siri/>", nil)
+
+	// Then
+	require.NoError(t, withCertErr)
+	assert.Equal(t, http.StatusOK, withCert.StatusCode)
+	assert.Error(t, withoutCertErr)
+	assert.Error(t, otherCertErr)
+}
+
+func Test_missing_certificates_are_an_error(t *testing.T) {
+	// When
+	_, clientErr := TLSConfig{CAFile: "missing.pem"}.ClientConfig()
+	_, serverErr := ServerTLSConfig{CertFile: "missing.pem", KeyFile: "missing.key"}.Config()
+
+	// Then
+	assert.Error(t, clientErr)
+	assert.ErrorContains(t, serverErr, "could not load server certificate")
+}
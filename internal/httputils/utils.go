@@ -3,8 +3,10 @@ package httputils
 import (
 	"fmt"
 	"io"
+	"maps"
 	"mime"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -16,6 +18,36 @@ const (
 	HeaderContentType = "Content-Type"
 )
 
+// ParseHeaders parses lines like "Name: value", empty lines are ignored
+func ParseHeaders(text string) (http.Header, error) {
+	header := http.Header{}
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, value, found := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid header in line %d, use Name: value", i+1)
+		}
+		header.Add(name, strings.TrimSpace(value))
+	}
+	return header, nil
+}
+
+// FormatHeaders formats the header as "Name: value" lines sorted by name
+func FormatHeaders(header http.Header) string {
+	names := slices.Sorted(maps.Keys(header))
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		for _, value := range header[name] {
+			lines = append(lines, name+": "+value)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 // GetLanguage extracts the language from the Content-Type header
 func GetLanguage(header http.Header) string {
 	contentType := header.Get(HeaderContentType)
@@ -32,17 +64,40 @@ func GetLanguage(header http.Header) string {
 	return parts[1]
 }
 
-func logRequest(writer io.Writer, logHeading string, request *http.Request, body []byte) {
+// logRequest logs the request with its decompressed body.
+// encodedSize is the size of the compressed body on the wire or 0 if it was not compressed.
+func logRequest(writer io.Writer, logHeading string, request *http.Request, body []byte, encodedSize int) {
 	fmt.Fprintf(writer, "%s\n", logHeading)
 	fmt.Fprintf(writer, "IP %s\n", request.RemoteAddr)
 	fmt.Fprintf(writer, "%s %s%s \n", request.Method, request.Host, request.URL.RequestURI())
-	fmt.Fprintf(writer, "Content-Type %s\n\n", request.Header.Get(HeaderContentType))
+	fmt.Fprintf(writer, "Content-Type %s\n", request.Header.Get(HeaderContentType))
+	logEncoding(writer, request.Header.Get(HeaderContentEncoding), encodedSize, len(body))
+	fmt.Fprintln(writer)
 	fmt.Fprintf(writer, "%s\n\n", string(body))
 }
 
-func logResponse(writer io.Writer, logHeading string, statusCode int, header http.Header, body []byte) {
+// logResponse logs the response with its decompressed body.
+// encodedSize is the size of the compressed body on the wire or 0 if it was not compressed.
+func logResponse(
+	writer io.Writer,
+	logHeading string,
+	statusCode int,
+	header http.Header,
+	body []byte,
+	encodedSize int,
+) {
 	fmt.Fprintf(writer, "%s\n", logHeading)
 	fmt.Fprintf(writer, "%s\n", strconv.Itoa(statusCode))
-	fmt.Fprintf(writer, "Content-Type %s\n\n", header.Get(HeaderContentType))
+	fmt.Fprintf(writer, "Content-Type %s\n", header.Get(HeaderContentType))
+	logEncoding(writer, header.Get(HeaderContentEncoding), encodedSize, len(body))
+	fmt.Fprintln(writer)
 	fmt.Fprintf(writer, "%s\n\n", string(body))
 }
+
+// logEncoding logs the compressed and decompressed size of compressed bodies
+func logEncoding(writer io.Writer, encoding string, encodedSize int, size int) {
+	if encodedSize == 0 {
+		return
+	}
+	fmt.Fprintf(writer, "Content-Encoding %s, %d bytes compressed, %d bytes decompressed\n", encoding, encodedSize, size)
+}
@@ -0,0 +1,48 @@
+package httputils
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parse_and_format_headers(t *testing.T) {
+	// Given
+	text := "x-correlation-id: 42\n\nContent-Type: text/xml; charset=ISO-8859-1\nAccept: a\nAccept: b\n"
+
+	// When
+	header, err := ParseHeaders(text)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, http.Header{
+		"X-Correlation-Id": {"42"},
+		"Content-Type":     {"text/xml; charset=ISO-8859-1"},
+		"Accept":           {"a", "b"},
+	}, header)
+	assert.Equal(
+		t,
+		"Accept: a\nAccept: b\nContent-Type: text/xml; charset=ISO-8859-1\nX-Correlation-Id: 42",
+		FormatHeaders(header),
+	)
+}
+
+func Test_invalid_headers_are_an_error(t *testing.T) {
+	testCases := map[string]string{
+		"no colon":        "Content-Type\n",
+		"no name":         ": value",
+		"space in name":   "Content Type: text/xml",
+		"error in line 2": "Accept: a\nbroken",
+	}
+	for name, text := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// When
+			_, err := ParseHeaders(text)
+
+			// Then
+			assert.ErrorContains(t, err, "invalid header in line")
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Package profile contains named server configurations like dev, staging or a partner system
+package profile
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/mszalbach/sirigo/internal/httputils"
+	"github.com/mszalbach/sirigo/internal/siri"
+	"gopkg.in/yaml.v3"
+)
+
+// Profile is the configuration of one server environment
+type Profile struct {
+	Name      string `yaml:"name"`
+	URL       string `yaml:"url"`
+	ClientRef string `yaml:"clientRef"`
+	// Port where the client is listening for server requests, only used when starting Sirigo
+	Port string `yaml:"port,omitempty"`
+	// Templates is the folder of the request templates
+	Templates string `yaml:"templates,omitempty"`
+	// Autoresponse is the folder of the autoresponse templates, only used when starting Sirigo
+	Autoresponse string `yaml:"autoresponse,omitempty"`
+	// Headers are sent with every request
+	Headers map[string]string `yaml:"headers,omitempty"`
+	TLS     TLS               `yaml:"tls,omitempty"`
+	Auth    Auth              `yaml:"auth,omitempty"`
+}
+
+// TLS configures how the server certificate is verified and which client certificate is used
+type TLS struct {
+	CA                 string `yaml:"ca,omitempty"`
+	Cert               string `yaml:"cert,omitempty"`
+	Key                string `yaml:"key,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify,omitempty"`
+}
+
+// Auth types
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthAPIKey = "apikey"
+	AuthOAuth2 = "oauth2"
+)
+
+// defaultAPIKeyHeader is used for API keys without a header
+const defaultAPIKeyHeader = "X-API-Key"
+
+// Auth configures how requests are authenticated. Secrets can reference environment variables like ${PARTNER_SECRET}.
+type Auth struct {
+	// Type is basic, bearer, apikey or oauth2, requests are not authenticated if it is empty
+	Type     string `yaml:"type,omitempty"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	Token    string `yaml:"token,omitempty"`
+	// Header is the name of the API key header, X-API-Key if not set
+	Header       string   `yaml:"header,omitempty"`
+	Key          string   `yaml:"key,omitempty"`
+	TokenURL     string   `yaml:"tokenURL,omitempty"`
+	ClientID     string   `yaml:"clientID,omitempty"`
+	ClientSecret string   `yaml:"clientSecret,omitempty"`
+	Scopes       []string `yaml:"scopes,omitempty"`
+}
+
+// Authenticator creates the Authenticator of the configuration, it is nil if no type is set
+func (a Auth) Authenticator() (httputils.Authenticator, error) {
+	switch a.Type {
+	case "":
+		return nil, nil
+	case AuthBasic:
+		return httputils.BasicAuth{Username: os.ExpandEnv(a.Username), Password: os.ExpandEnv(a.Password)}, nil
+	case AuthBearer:
+		return httputils.BearerToken{Token: os.ExpandEnv(a.Token)}, nil
+	case AuthAPIKey:
+		return httputils.APIKey{Name: cmp.Or(a.Header, defaultAPIKeyHeader), Value: os.ExpandEnv(a.Key)}, nil
+	case AuthOAuth2:
+		if a.TokenURL == "" {
+			return nil, errors.New("oauth2 requires a tokenURL")
+		}
+		return httputils.NewOAuth2ClientCredentials(
+			a.TokenURL,
+			os.ExpandEnv(a.ClientID),
+			os.ExpandEnv(a.ClientSecret),
+			a.Scopes,
+		), nil
+	}
+	return nil, fmt.Errorf("unknown auth type %q, use basic, bearer, apikey or oauth2", a.Type)
+}
+
+// file is the structure of the profiles file
+type file struct {
+	Profiles []Profile `yaml:"profiles"`
+}
+
+// Load reads the profiles from a YAML file
+func Load(path string) ([]Profile, error) {
+	content, err := os.ReadFile(path) //nolint gosec // the profiles file is chosen by the user
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := yaml.Unmarshal(content, &f); err != nil {
+		return nil, fmt.Errorf("could not parse profiles %s: %w", path, err)
+	}
+	if err := validate(f.Profiles); err != nil {
+		return nil, fmt.Errorf("invalid profiles %s: %w", path, err)
+	}
+	return f.Profiles, nil
+}
+
+func validate(profiles []Profile) error {
+	if len(profiles) == 0 {
+		return errors.New("no profiles defined")
+	}
+	names := map[string]bool{}
+	for i, profile := range profiles {
+		switch {
+		case profile.Name == "":
+			return fmt.Errorf("profile %d has no name", i+1)
+		case names[profile.Name]:
+			return fmt.Errorf("profile %s is defined twice", profile.Name)
+		case profile.URL == "":
+			return fmt.Errorf("profile %s has no url", profile.Name)
+		case profile.ClientRef == "":
+			return fmt.Errorf("profile %s has no clientRef", profile.Name)
+		}
+		if _, err := profile.Auth.Authenticator(); err != nil {
+			return fmt.Errorf("profile %s: %w", profile.Name, err)
+		}
+		names[profile.Name] = true
+	}
+	return nil
+}
+
+// Find returns the profile with the name
+func Find(profiles []Profile, name string) (Profile, error) {
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return profile, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("unknown profile %q", name)
+}
+
+// Header returns the headers of the profile as http.Header
+func (p Profile) Header() http.Header {
+	header := http.Header{}
+	for name, value := range p.Headers {
+		header.Set(name, value)
+	}
+	return header
+}
+
+// TLSOptions are the TLS settings configured besides the profiles
+type TLSOptions struct {
+	// Base are the settings of the config file, environment and command line
+	Base httputils.TLSConfig
+	// Explicit marks the settings of the environment and command line, the profile does not replace them
+	Explicit ExplicitTLS
+}
+
+// ExplicitTLS marks which TLS settings were set explicitly
+type ExplicitTLS struct {
+	CA                 bool
+	Cert               bool
+	Key                bool
+	InsecureSkipVerify bool
+}
+
+// TLSConfig returns the TLS settings used for requests to the server.
+// The settings of the profile replace the base settings unless they were set explicitly.
+func (p Profile) TLSConfig(options TLSOptions) httputils.TLSConfig {
+	config := options.Base
+	for _, setting := range []struct {
+		target   *string
+		profile  string
+		explicit bool
+	}{
+		{&config.CAFile, p.TLS.CA, options.Explicit.CA},
+		{&config.CertFile, p.TLS.Cert, options.Explicit.Cert},
+		{&config.KeyFile, p.TLS.Key, options.Explicit.Key},
+	} {
+		if setting.profile != "" && !setting.explicit {
+			*setting.target = setting.profile
+		}
+	}
+	if p.TLS.InsecureSkipVerify && !options.Explicit.InsecureSkipVerify {
+		config.InsecureSkipVerify = true
+	}
+	return config
+}
+
+// Apply switches the client to the server of the profile, the TLS settings are merged with the options.
+// The client is not changed if the profile cannot be used.
+func (p Profile) Apply(siriClient *siri.Client, options TLSOptions) error {
+	auth, err := p.Auth.Authenticator()
+	if err != nil {
+		return fmt.Errorf("could not use auth of profile %s: %w", p.Name, err)
+	}
+	if err := siriClient.UseServer(p.URL, p.ClientRef, p.Header(), p.TLSConfig(options), auth); err != nil {
+		return fmt.Errorf("could not use TLS settings of profile %s: %w", p.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package profile
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mszalbach/sirigo/internal/httputils"
+	"github.com/mszalbach/sirigo/internal/siri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_load_profiles(t *testing.T) {
+	// When
+	profiles, err := Load("testdata/profiles.yaml")
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, Profile{
+		Name:      "dev",
+		URL:       "http://localhost:8080",
+		ClientRef: "dev-client",
+		Port:      ":8000",
+		Templates: "templates/siri/request",
+	}, profiles[0])
+	assert.Equal(t, "secret", profiles[1].Header().Get("X-Api-Key"))
+	assert.Equal(t, httputils.TLSConfig{InsecureSkipVerify: true}, profiles[1].TLSConfig(TLSOptions{}))
+}
+
+func Test_invalid_profiles_are_an_error(t *testing.T) {
+	testCases := map[string]struct {
+		content  string
+		expected string
+	}{
+		"no profiles":  {"profiles: []", "no profiles defined"},
+		"no name":      {"profiles: [{url: http://dev, clientRef: c}]", "profile 1 has no name"},
+		"no url":       {"profiles: [{name: dev, clientRef: c}]", "profile dev has no url"},
+		"no clientRef": {"profiles: [{name: dev, url: http://dev}]", "profile dev has no clientRef"},
+		"duplicate": {
+			"profiles: [{name: dev, url: http://dev, clientRef: c}, {name: dev, url: http://dev, clientRef: c}]",
+			"profile dev is defined twice",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			file := filepath.Join(t.TempDir(), "profiles.yaml")
+			require.NoError(t, os.WriteFile(file, []byte(tc.content), 0o600))
+
+			// When
+			_, err := Load(file)
+
+			// Then
+			assert.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
+
+func Test_find_profile(t *testing.T) {
+	// Given
+	profiles := []Profile{{Name: "dev"}, {Name: "staging"}}
+
+	// When
+	found, err := Find(profiles, "staging")
+	_, notFoundErr := Find(profiles, "prod")
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, "staging", found.Name)
+	assert.EqualError(t, notFoundErr, `unknown profile "prod"`)
+}
+
+func Test_apply_switches_client_to_profile(t *testing.T) {
+	// Given
+	client := siri.NewClient("client", "http://localhost:8080", "CLIENT ADDRESS", io.Discard)
+	p := Profile{
+		Name:      "partner",
+		URL:       "https://partner.example.com",
+		ClientRef: "sirigo",
+		Headers:   map[string]string{"X-Api-Key": "secret"},
+	}
+
+	// When
+	err := p.Apply(&client, TLSOptions{})
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, "https://partner.example.com", client.ServerURL)
+	assert.Equal(t, "sirigo", client.ClientRef)
+	assert.Equal(t, "secret", client.Header.Get("X-Api-Key"))
+}
+
+func Test_apply_fails_for_missing_certificates(t *testing.T) {
+	// Given
+	client := siri.NewClient("client", "http://localhost:8080", "CLIENT ADDRESS", io.Discard)
+	p := Profile{Name: "partner", URL: "https://partner.example.com", ClientRef: "sirigo", TLS: TLS{CA: "missing.pem"}}
+
+	// When
+	err := p.Apply(&client, TLSOptions{})
+
+	// Then
+	require.Error(t, err)
+	assert.Equal(t, "http://localhost:8080", client.ServerURL)
+}
+
+func Test_tls_config_merges_options(t *testing.T) {
+	p := Profile{Name: "partner", TLS: TLS{CA: "partner-ca.pem", Cert: "partner.pem", InsecureSkipVerify: true}}
+	base := httputils.TLSConfig{CAFile: "ca.pem", CertFile: "client.pem", KeyFile: "client-key.pem"}
+	testCases := map[string]struct {
+		options  TLSOptions
+		expected httputils.TLSConfig
+	}{
+		"profile replaces base": {
+			TLSOptions{Base: base},
+			httputils.TLSConfig{
+				CAFile:             "partner-ca.pem",
+				CertFile:           "partner.pem",
+				KeyFile:            "client-key.pem",
+				InsecureSkipVerify: true,
+			},
+		},
+		"explicit options win": {
+			TLSOptions{Base: base, Explicit: ExplicitTLS{CA: true, Cert: true, InsecureSkipVerify: true}},
+			base,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// When
+			actual := p.TLSConfig(tc.options)
+
+			// Then
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func Test_auth_creates_authenticator(t *testing.T) {
+	t.Setenv("PARTNER_SECRET", "secret")
+	testCases := map[string]struct {
+		auth     Auth
+		expected httputils.Authenticator
+	}{
+		"none": {Auth{}, nil},
+		"basic": {
+			Auth{Type: AuthBasic, Username: "user", Password: "${PARTNER_SECRET}"},
+			httputils.BasicAuth{Username: "user", Password: "secret"},
+		},
+		"bearer":  {Auth{Type: AuthBearer, Token: "$PARTNER_SECRET"}, httputils.BearerToken{Token: "secret"}},
+		"api key": {Auth{Type: AuthAPIKey, Key: "key"}, httputils.APIKey{Name: "X-API-Key", Value: "key"}},
+		"custom api key header": {
+			Auth{Type: AuthAPIKey, Header: "Ocp-Apim-Subscription-Key", Key: "key"},
+			httputils.APIKey{Name: "Ocp-Apim-Subscription-Key", Value: "key"},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// When
+			actual, err := tc.auth.Authenticator()
+
+			// Then
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func Test_invalid_auth_is_an_error(t *testing.T) {
+	// When
+	_, unknownErr := Auth{Type: "digest"}.Authenticator()
+	_, oauth2Err := Auth{Type: AuthOAuth2, ClientID: "sirigo"}.Authenticator()
+	oauth2, validErr := Auth{Type: AuthOAuth2, TokenURL: "https://auth.example.com/token"}.Authenticator()
+
+	// Then
+	assert.ErrorContains(t, unknownErr, `unknown auth type "digest"`)
+	assert.ErrorContains(t, oauth2Err, "oauth2 requires a tokenURL")
+	require.NoError(t, validErr)
+	assert.IsType(t, &httputils.OAuth2ClientCredentials{}, oauth2)
+}
@@ -0,0 +1,86 @@
+package scenario
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// WriteReport writes a human readable report of all results
+func WriteReport(w io.Writer, results []Result) {
+	passed := 0
+	for _, result := range results {
+		fmt.Fprintf(w, "=== %s\n", result.Name)
+		for _, step := range result.Steps {
+			switch {
+			case step.Skipped:
+				fmt.Fprintf(w, "--- SKIP %s\n", step.Name)
+			case step.Err != nil:
+				fmt.Fprintf(w, "--- FAIL %s (%s): %v\n", step.Name, step.Duration.Round(time.Millisecond), step.Err)
+			default:
+				fmt.Fprintf(w, "--- PASS %s (%s)\n", step.Name, step.Duration.Round(time.Millisecond))
+			}
+		}
+		if result.Passed() {
+			passed++
+			fmt.Fprintf(w, "PASS %s (%s)\n\n", result.Name, result.Duration.Round(time.Millisecond))
+		} else {
+			fmt.Fprintf(w, "FAIL %s (%s)\n\n", result.Name, result.Duration.Round(time.Millisecond))
+		}
+	}
+	fmt.Fprintf(w, "%d of %d scenarios passed\n", passed, len(results))
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     float64         `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name    string        `xml:"name,attr"`
+	Time    float64       `xml:"time,attr"`
+	Failure *junitFailure `xml:"failure,omitempty"`
+	Skipped *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes all results as JUnit XML report, one test suite per scenario and one test case per step
+func WriteJUnit(w io.Writer, results []Result) error {
+	report := junitTestSuites{}
+	for _, result := range results {
+		suite := junitTestSuite{Name: result.Name, Tests: len(result.Steps), Time: result.Duration.Seconds()}
+		for _, step := range result.Steps {
+			testCase := junitTestCase{Name: step.Name, Time: step.Duration.Seconds()}
+			switch {
+			case step.Skipped:
+				suite.Skipped++
+				testCase.Skipped = &struct{}{}
+			case step.Err != nil:
+				suite.Failures++
+				testCase.Failure = &junitFailure{Message: step.Err.Error()}
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+		report.Suites = append(report.Suites, suite)
+	}
+
+	content, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, content)
+	return err
+}
@@ -0,0 +1,213 @@
+package scenario
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mszalbach/sirigo/internal/siri"
+)
+
+// Result is the outcome of a scenario run
+type Result struct {
+	Name     string
+	Steps    []StepResult
+	Duration time.Duration
+}
+
+// StepResult is the outcome of a single step. Steps after a failed step are skipped.
+type StepResult struct {
+	Name     string
+	Err      error
+	Skipped  bool
+	Duration time.Duration
+}
+
+// Passed reports whether all steps passed
+func (r Result) Passed() bool {
+	for _, step := range r.Steps {
+		if step.Err != nil || step.Skipped {
+			return false
+		}
+	}
+	return true
+}
+
+// Runner executes scenarios with a SIRI client
+type Runner struct {
+	send        func(context.Context, siri.ClientRequest) (siri.ServerResponse, error)
+	templateURL func(name string, template string) string
+	requests    <-chan siri.ServerRequest
+	templates   siri.TemplateCache
+	inbox       inbox
+	collect     sync.Once
+}
+
+// NewRunner creates a Runner which sends templates with the client and waits for requests received by its listener.
+// The runner consumes all server requests of the client.
+func NewRunner(siriClient *siri.Client, templates siri.TemplateCache) *Runner {
+	return &Runner{
+		send:        siriClient.Send,
+		templateURL: siriClient.TemplateURL,
+		requests:    siriClient.ServerRequest,
+		templates:   templates,
+		inbox:       inbox{arrived: make(chan struct{}, 1)},
+	}
+}
+
+// Run executes all steps of the scenario in order and stops at the first failing step
+func (r *Runner) Run(ctx context.Context, scenario Scenario) Result {
+	// collect requests already before a wait step, since the server may be faster than the next step
+	r.collect.Do(func() {
+		go r.inbox.collect(r.requests)
+	})
+	r.inbox.clear()
+
+	start := time.Now()
+	result := Result{Name: scenario.Name}
+	failed := false
+	for _, step := range scenario.Steps {
+		if failed {
+			result.Steps = append(result.Steps, StepResult{Name: step.name(), Skipped: true})
+			continue
+		}
+		stepStart := time.Now()
+		err := r.runStep(ctx, step)
+		result.Steps = append(result.Steps, StepResult{Name: step.name(), Err: err, Duration: time.Since(stepStart)})
+		failed = err != nil
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+func (r *Runner) runStep(ctx context.Context, step Step) error {
+	if step.Wait != nil {
+		return r.inbox.waitFor(ctx, *step.Wait)
+	}
+
+	requestTemplate, err := r.templates.GetTemplate(step.Send)
+	if err != nil {
+		return err
+	}
+	response, err := r.send(ctx, siri.ClientRequest{
+		URL:    cmp.Or(step.URL, r.templateURL(step.Send, requestTemplate)),
+		Body:   requestTemplate,
+		Header: siri.GetHeadersFromTemplate(requestTemplate),
+	})
+	if err != nil {
+		return err
+	}
+	return step.Expect.check(response)
+}
+
+// check returns an error describing the first unmet expectation
+func (e *Expectation) check(response siri.ServerResponse) error {
+	expectation := Expectation{}
+	if e != nil {
+		expectation = *e
+	}
+
+	if expectation.Status == 0 && (response.Status < 200 || response.Status > 299) {
+		return fmt.Errorf("expected a 2xx HTTP status but got %d", response.Status)
+	}
+	if expectation.Status != 0 && expectation.Status != response.Status {
+		return fmt.Errorf("expected HTTP status %d but got %d", expectation.Status, response.Status)
+	}
+
+	status, found := response.MessageStatus()
+	if expectation.SiriStatus == nil && found && !status {
+		return fmt.Errorf("%s reported status false", response.MessageName())
+	}
+	if expectation.SiriStatus != nil && (!found || status != *expectation.SiriStatus) {
+		return fmt.Errorf("expected SIRI status %t in %q", *expectation.SiriStatus, response.MessageName())
+	}
+
+	matcher := siri.RequestMatcher{Element: expectation.Element, Selector: expectation.Selector}
+	if !matcher.Matches("", response.Body) {
+		return fmt.Errorf("response does not match element %q and selector %q", matcher.Element, matcher.Selector)
+	}
+	for _, text := range expectation.Contains {
+		if !strings.Contains(response.Body, text) {
+			return fmt.Errorf("response does not contain %q", text)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the server request matches all conditions of the wait step
+func (w Wait) matches(request siri.ServerRequest) bool {
+	urlPath := request.URL
+	if u, err := url.ParseRequestURI(request.URL); err == nil {
+		urlPath = u.Path
+	}
+	if !w.Matches(urlPath, request.Body) {
+		return false
+	}
+	for _, text := range w.Contains {
+		if !strings.Contains(request.Body, text) {
+			return false
+		}
+	}
+	return true
+}
+
+// inbox keeps the server requests which were not consumed by a wait step
+type inbox struct {
+	mu       sync.Mutex
+	requests []siri.ServerRequest
+	arrived  chan struct{}
+}
+
+func (i *inbox) collect(requests <-chan siri.ServerRequest) {
+	for request := range requests {
+		i.mu.Lock()
+		i.requests = append(i.requests, request)
+		i.mu.Unlock()
+		select {
+		case i.arrived <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (i *inbox) clear() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.requests = nil
+}
+
+// take removes and returns the first matching request and all requests received before it
+func (i *inbox) take(wait Wait) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for index, request := range i.requests {
+		if wait.matches(request) {
+			i.requests = i.requests[index+1:]
+			return true
+		}
+	}
+	return false
+}
+
+func (i *inbox) waitFor(ctx context.Context, wait Wait) error {
+	timeout := cmp.Or(wait.Timeout, defaultWaitTimeout)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		if i.take(wait) {
+			return nil
+		}
+		select {
+		case <-i.arrived:
+		case <-timer.C:
+			return fmt.Errorf("no matching server request received within %s", timeout)
+		case <-ctx.Done():
+			return errors.Join(errors.New("waiting for server request canceled"), ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mszalbach/sirigo/internal/siri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const subscriptionResponse = `<Siri>
+	<SubscriptionResponse>
+		<ResponseStatus>
+			<SubscriptionRef>1</SubscriptionRef>
+			<Status>true</Status>
+		</ResponseStatus>
+	</SubscriptionResponse>
+</Siri>`
+
+func newTestRunner(t *testing.T, serverURL string) (*Runner, chan siri.ServerRequest) {
+	t.Helper()
+	templates, err := siri.NewTemplateCache("testdata/templates")
+	require.NoError(t, err)
+	client := siri.NewClient("CLIENT REF", serverURL, "CLIENT ADDRESS", io.Discard)
+	runner := NewRunner(&client, templates)
+	requests := make(chan siri.ServerRequest, 5)
+	runner.requests = requests
+	return runner, requests
+}
+
+func newTestServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/siri/et.xml", req.URL.String())
+		rw.Header().Set("Content-Type", "application/xml")
+		rw.WriteHeader(status)
+		fmt.Fprint(rw, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func Test_loads_scenario_from_yaml(t *testing.T) {
+	// When
+	actual, err := Load("testdata/scenario.yaml")
+	require.NoError(t, err)
+
+	// Then
+	siriStatus := true
+	expected := Scenario{
+		Name: "ET subscription",
+		Steps: []Step{
+			{
+				Name: "subscribe",
+				Send: "subscribe.xml",
+				Expect: &Expectation{
+					Status:     http.StatusOK,
+					SiriStatus: &siriStatus,
+					Element:    "SubscriptionResponse",
+					Contains:   []string{"<SubscriptionRef>1</SubscriptionRef>"},
+				},
+			},
+			{
+				Name: "wait for data ready",
+				Wait: &Wait{
+					RequestMatcher: siri.RequestMatcher{Path: "/siri/*", Element: "DataReadyNotification"},
+					Timeout:        5 * time.Second,
+				},
+			},
+		},
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func Test_invalid_scenarios_are_not_loaded(t *testing.T) {
+	_, err := Load("testdata/invalid.yaml")
+
+	require.ErrorContains(t, err, "must either send or wait")
+}
+
+func Test_runs_all_steps_of_a_scenario(t *testing.T) {
+	// Given
+	server := newTestServer(t, http.StatusOK, subscriptionResponse)
+	runner, requests := newTestRunner(t, server.URL)
+	scenario, err := Load("testdata/scenario.yaml")
+	require.NoError(t, err)
+
+	// When
+	requests <- siri.ServerRequest{URL: "/siri/et", Body: "<Siri><HeartbeatNotification/></Siri>"}
+	requests <- siri.ServerRequest{URL: "/siri/et", Body: "<Siri><DataReadyNotification/></Siri>"}
+	actual := runner.Run(context.Background(), scenario)
+
+	// Then
+	assert.True(t, actual.Passed())
+	require.Len(t, actual.Steps, 2)
+	require.NoError(t, actual.Steps[0].Err)
+	require.NoError(t, actual.Steps[1].Err)
+}
+
+func Test_skips_steps_after_failed_step(t *testing.T) {
+	// Given
+	server := newTestServer(t, http.StatusInternalServerError, "")
+	runner, _ := newTestRunner(t, server.URL)
+	scenario, err := Load("testdata/scenario.yaml")
+	require.NoError(t, err)
+
+	// When
+	actual := runner.Run(context.Background(), scenario)
+
+	// Then
+	assert.False(t, actual.Passed())
+	require.EqualError(t, actual.Steps[0].Err, "expected HTTP status 200 but got 500")
+	assert.True(t, actual.Steps[1].Skipped)
+}
+
+func Test_send_step_checks_expectations(t *testing.T) {
+	siriStatusFalse := false
+	testCases := map[string]struct {
+		expect        *Expectation
+		response      string
+		expectedError string
+	}{
+		"no expectation": {nil, subscriptionResponse, ""},
+		"no expectation but failure": {
+			nil,
+			"<Siri><CheckStatusResponse/></Siri>",
+			"CheckStatusResponse reported status false",
+		},
+		"expected SIRI failure": {
+			&Expectation{SiriStatus: &siriStatusFalse},
+			subscriptionResponse,
+			`expected SIRI status false in "SubscriptionResponse"`,
+		},
+		"selector matches": {&Expectation{Selector: "//SubscriptionRef[text()='1']"}, subscriptionResponse, ""},
+		"selector does not match": {
+			&Expectation{Selector: "//SubscriptionRef[text()='2']"},
+			subscriptionResponse,
+			`response does not match element "" and selector "//SubscriptionRef[text()='2']"`,
+		},
+		"missing text": {
+			&Expectation{Contains: []string{"ERROR"}},
+			subscriptionResponse,
+			`response does not contain "ERROR"`,
+		},
+		"element does not match": {
+			&Expectation{Element: "ServiceDelivery"},
+			subscriptionResponse,
+			`response does not match element "ServiceDelivery" and selector ""`,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			server := newTestServer(t, http.StatusOK, tc.response)
+			runner, _ := newTestRunner(t, server.URL)
+			scenario := Scenario{Name: name, Steps: []Step{{Send: "subscribe.xml", Expect: tc.expect}}}
+
+			// When
+			actual := runner.Run(context.Background(), scenario)
+
+			// Then
+			if tc.expectedError == "" {
+				require.NoError(t, actual.Steps[0].Err)
+			} else {
+				require.EqualError(t, actual.Steps[0].Err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func Test_wait_step_fails_after_timeout(t *testing.T) {
+	// Given
+	runner, requests := newTestRunner(t, "SERVER URL")
+	scenario := Scenario{Steps: []Step{{Wait: &Wait{
+		RequestMatcher: siri.RequestMatcher{Element: "DataReadyNotification"},
+		Timeout:        50 * time.Millisecond,
+	}}}}
+
+	// When
+	requests <- siri.ServerRequest{URL: "/siri", Body: "<Siri><HeartbeatNotification/></Siri>"}
+	actual := runner.Run(context.Background(), scenario)
+
+	// Then
+	require.EqualError(t, actual.Steps[0].Err, "no matching server request received within 50ms")
+}
+
+func Test_writes_reports(t *testing.T) {
+	// Given
+	results := []Result{{
+		Name: "ET",
+		Steps: []StepResult{
+			{Name: "subscribe", Duration: time.Second},
+			{Name: "wait", Err: fmt.Errorf("timeout"), Duration: 2 * time.Second},
+			{Name: "terminate", Skipped: true},
+		},
+		Duration: 3 * time.Second,
+	}}
+
+	// When
+	var report bytes.Buffer
+	WriteReport(&report, results)
+	var junit bytes.Buffer
+	require.NoError(t, WriteJUnit(&junit, results))
+
+	// Then
+	assert.Equal(t, `=== ET
+--- PASS subscribe (1s)
+--- FAIL wait (2s): timeout
+--- SKIP terminate
+FAIL ET (3s)
+
+0 of 1 scenarios passed
+`, report.String())
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="ET" tests="3" failures="1" skipped="1" time="3">
+    <testcase name="subscribe" time="1"></testcase>
+    <testcase name="wait" time="2">
+      <failure message="timeout"></failure>
+    </testcase>
+    <testcase name="terminate" time="0">
+      <skipped></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, junit.String())
+}
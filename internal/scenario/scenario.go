@@ -0,0 +1,97 @@
+// Package scenario runs scripted multi-step SIRI conversations like subscribe, wait for DataReady and fetch data
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mszalbach/sirigo/internal/siri"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultWaitTimeout is used for wait steps without a timeout
+const defaultWaitTimeout = 30 * time.Second
+
+// Scenario is an ordered list of steps executed one after another
+type Scenario struct {
+	Name  string `yaml:"name"`
+	Steps []Step `yaml:"steps"`
+}
+
+// Step either sends a template to the SIRI server or waits for a request of the SIRI server
+type Step struct {
+	Name string `yaml:"name"`
+	// Send is the name of the template to send, relative to the templates folder
+	Send string `yaml:"send,omitempty"`
+	// URL overrides the URL which is created from the server URL and the path comment of the template
+	URL    string       `yaml:"url,omitempty"`
+	Expect *Expectation `yaml:"expect,omitempty"`
+	Wait   *Wait        `yaml:"wait,omitempty"`
+}
+
+// Expectation is checked against the response of a send step.
+// Without an expectation a 2xx HTTP status and no SIRI status false is expected.
+type Expectation struct {
+	// Status is the expected HTTP status, any 2xx status if not set
+	Status int `yaml:"status,omitempty"`
+	// SiriStatus is the expected status within the SIRI message, it must not be false if not set
+	SiriStatus *bool    `yaml:"siriStatus,omitempty"`
+	Element    string   `yaml:"element,omitempty"`
+	Selector   string   `yaml:"selector,omitempty"`
+	Contains   []string `yaml:"contains,omitempty"`
+}
+
+// Wait waits until the SIRI server sends a request matching all set conditions
+type Wait struct {
+	siri.RequestMatcher `yaml:",inline"`
+
+	Contains []string      `yaml:"contains,omitempty"`
+	Timeout  time.Duration `yaml:"timeout,omitempty"`
+}
+
+// Load reads a scenario from a YAML file
+func Load(file string) (Scenario, error) {
+	content, err := os.ReadFile(file) //nolint gosec // the scenario file is chosen by the user
+	if err != nil {
+		return Scenario{}, err
+	}
+	var scenario Scenario
+	if err := yaml.Unmarshal(content, &scenario); err != nil {
+		return Scenario{}, fmt.Errorf("could not parse scenario %s: %w", file, err)
+	}
+	if scenario.Name == "" {
+		scenario.Name = file
+	}
+	if err := scenario.validate(); err != nil {
+		return Scenario{}, fmt.Errorf("invalid scenario %s: %w", file, err)
+	}
+	return scenario, nil
+}
+
+func (s Scenario) validate() error {
+	if len(s.Steps) == 0 {
+		return errors.New("no steps defined")
+	}
+	for i, step := range s.Steps {
+		if (step.Send == "") == (step.Wait == nil) {
+			return fmt.Errorf("step %d (%s) must either send or wait", i+1, step.Name)
+		}
+		if step.Wait != nil && step.Expect != nil {
+			return fmt.Errorf("step %d (%s): expect is only supported for send steps", i+1, step.Name)
+		}
+	}
+	return nil
+}
+
+// name returns a readable name for the step
+func (s Step) name() string {
+	switch {
+	case s.Name != "":
+		return s.Name
+	case s.Send != "":
+		return "send " + s.Send
+	}
+	return "wait"
+}
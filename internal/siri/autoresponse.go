@@ -0,0 +1,138 @@
+package siri
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+	"sync"
+)
+
+// AutoResponseRulesFile is the name of the file in the autoresponse folder which contains the rules
+const AutoResponseRulesFile = "rules.json"
+
+// RequestMatcher matches incoming server requests.
+// All conditions which are set must match, an empty condition matches everything.
+type RequestMatcher struct {
+	// Path is a pattern for the URL path like /siri/* (see path.Match)
+	Path string `json:"path,omitempty"`
+	// Element is the name of the message element like CheckStatusRequest
+	Element string `json:"element,omitempty"`
+	// Selector is an XPath-like selector which must find an element in the request body
+	Selector string `json:"selector,omitempty"`
+}
+
+// AutoResponseRule maps incoming server requests to an automatic response
+type AutoResponseRule struct {
+	RequestMatcher
+	// Template is the name of the autoresponse template, an empty template results in an empty body
+	Template string `json:"template,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	// Body is the content of the Template
+	Body string `json:"-"`
+}
+
+// AutoResponseRules selects the automatic response for an incoming server request
+type AutoResponseRules struct {
+	mu    sync.RWMutex
+	rules []AutoResponseRule
+}
+
+// Rules returns a copy of all rules in the order they are checked
+func (ar *AutoResponseRules) Rules() []AutoResponseRule {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+	return slices.Clone(ar.rules)
+}
+
+// SetRules replaces all rules. The first matching rule is used for a request.
+func (ar *AutoResponseRules) SetRules(rules []AutoResponseRule) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	ar.rules = slices.Clone(rules)
+}
+
+// match returns the first rule matching the URL path and body of a request
+func (ar *AutoResponseRules) match(urlPath string, body string) (AutoResponseRule, bool) {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+	for _, rule := range ar.rules {
+		if rule.Matches(urlPath, body) {
+			return rule, true
+		}
+	}
+	return AutoResponseRule{}, false
+}
+
+// Matches reports whether the URL path and body of a request match all set conditions
+func (m RequestMatcher) Matches(urlPath string, body string) bool {
+	if m.Path != "" {
+		if matched, err := path.Match(m.Path, urlPath); err != nil || !matched {
+			return false
+		}
+	}
+	if m.Element != "" && m.Element != MessageElementName(body) {
+		return false
+	}
+	if m.Selector != "" {
+		matched, err := MatchesSelector(body, m.Selector)
+		if err != nil {
+			slog.Warn("Could not evaluate selector", slog.String("selector", m.Selector), slog.Any("error", err))
+			return false
+		}
+		return matched
+	}
+	return true
+}
+
+// LoadAutoResponseRules reads the rules file from the autoresponse folder and the content of their templates.
+// Returns no rules if there is no rules file.
+func LoadAutoResponseRules(templates TemplateCache) ([]AutoResponseRule, error) {
+	content, err := templates.GetTemplate(AutoResponseRulesFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []AutoResponseRule
+	if err := json.Unmarshal([]byte(content), &rules); err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if err := rules[i].LoadTemplate(templates); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// SaveAutoResponseRules writes the rules into the rules file of the autoresponse folder
+func SaveAutoResponseRules(templates TemplateCache, rules []AutoResponseRule) error {
+	content, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return err
+	}
+	return templates.root.WriteFile(AutoResponseRulesFile, content, 0o600)
+}
+
+// LoadTemplate sets the Body to the content of the Template and the Status to 200 if it is not set
+func (r *AutoResponseRule) LoadTemplate(templates TemplateCache) error {
+	if r.Status == 0 {
+		r.Status = http.StatusOK
+	}
+	r.Body = ""
+	if r.Template == "" {
+		return nil
+	}
+	body, err := templates.GetTemplate(r.Template)
+	if err != nil {
+		return err
+	}
+	r.Body = body
+	return nil
+}
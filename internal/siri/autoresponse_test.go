@@ -0,0 +1,117 @@
+package siri
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_first_matching_auto_response_rule_is_used(t *testing.T) {
+	// Given
+	rules := AutoResponseRules{}
+	rules.SetRules([]AutoResponseRule{
+		{RequestMatcher: RequestMatcher{Path: "/vdv/*"}, Body: "vdv", Status: http.StatusOK},
+		{RequestMatcher: RequestMatcher{Element: "CheckStatusRequest"}, Body: "check status", Status: http.StatusOK},
+		{
+			RequestMatcher: RequestMatcher{Selector: "//DataReadyNotification[ProducerRef='broken']"},
+			Body:           "broken",
+			Status:         http.StatusBadRequest,
+		},
+		{RequestMatcher: RequestMatcher{Element: "DataReadyNotification"}, Body: "data ready", Status: http.StatusOK},
+	})
+
+	testCases := map[string]struct {
+		urlPath      string
+		body         string
+		expectedBody string
+		expectedOk   bool
+	}{
+		"matches path": {
+			"/vdv/test",
+			"<Siri><CheckStatusRequest/></Siri>",
+			"vdv",
+			true,
+		},
+		"matches element": {
+			"/siri",
+			"<Siri><CheckStatusRequest/></Siri>",
+			"check status",
+			true,
+		},
+		"matches selector": {
+			"/siri",
+			"<Siri><DataReadyNotification><ProducerRef>broken</ProducerRef></DataReadyNotification></Siri>",
+			"broken",
+			true,
+		},
+		"selector does not match": {
+			"/siri",
+			"<Siri><DataReadyNotification><ProducerRef>ok</ProducerRef></DataReadyNotification></Siri>",
+			"data ready",
+			true,
+		},
+		"nothing matches": {
+			"/siri",
+			"<Siri><HeartbeatNotification/></Siri>",
+			"",
+			false,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual, ok := rules.match(tc.urlPath, tc.body)
+			assert.Equal(t, tc.expectedOk, ok)
+			assert.Equal(t, tc.expectedBody, actual.Body)
+		})
+	}
+}
+
+func Test_loads_and_saves_auto_response_rules(t *testing.T) {
+	// Given
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "checkStatus.xml"), []byte("<Siri/>"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, AutoResponseRulesFile), []byte(`[
+		{"element": "CheckStatusRequest", "template": "checkStatus.xml"},
+		{"element": "HeartbeatNotification", "status": 204}
+	]`), 0o600))
+	templates, err := NewTemplateCache(dir)
+	require.NoError(t, err)
+
+	// When
+	rules, err := LoadAutoResponseRules(templates)
+	require.NoError(t, err)
+
+	// Then
+	expected := []AutoResponseRule{
+		{
+			RequestMatcher: RequestMatcher{Element: "CheckStatusRequest"},
+			Template:       "checkStatus.xml",
+			Status:         http.StatusOK,
+			Body:           "<Siri/>",
+		},
+		{RequestMatcher: RequestMatcher{Element: "HeartbeatNotification"}, Status: http.StatusNoContent},
+	}
+	assert.Equal(t, expected, rules)
+
+	// When saved and loaded again
+	require.NoError(t, SaveAutoResponseRules(templates, rules[:1]))
+	actual, err := LoadAutoResponseRules(templates)
+	require.NoError(t, err)
+
+	// Then
+	assert.Equal(t, expected[:1], actual)
+}
+
+func Test_no_auto_response_rules_without_rules_file(t *testing.T) {
+	templates, err := NewTemplateCache("testdata")
+	require.NoError(t, err)
+
+	actual, err := LoadAutoResponseRules(templates)
+
+	require.NoError(t, err)
+	assert.Empty(t, actual)
+}
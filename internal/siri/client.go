@@ -2,35 +2,77 @@
 package siri
 
 import (
+	"cmp"
 	"context"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/mszalbach/sirigo/internal/httputils"
+	"github.com/mszalbach/sirigo/internal/xsd"
 )
 
-// Client contains everything needed for a SIRI client
+// Client contains everything needed for a SIRI client.
+// ClientRef, ServerURL and Header must only be changed with UseServer once the client is running.
 type Client struct {
-	ClientRef           string
-	ServerURL           string
-	ServerRequest       <-chan ServerRequest
-	AutoClientResponse  *AutoClientResponse
-	serverRequestWriter chan ServerRequest
-	httpclient          httputils.LoggingClient
-	httpserver          *httputils.LoggingMuxServer
+	ClientRef          string
+	ServerURL          string
+	ServerRequest      <-chan ServerRequest
+	AutoClientResponse *AutoClientResponse
+	AutoResponseRules  *AutoResponseRules
+	Subscriptions      *Subscriptions
+	History            *History
+	// Deliveries are the ServiceDeliveries the server sent in direct delivery mode
+	Deliveries *Deliveries
+	// Heartbeats monitors the HeartbeatNotifications of the server, see MonitorHeartbeats
+	Heartbeats *Heartbeats
+	// Header contains additional headers sent with every request
+	Header http.Header
+	// ConsumerAddress is the URL the server sends its requests to, request templates use it as ConsumerAddress
+	ConsumerAddress string
+	// PushTargets are consumer endpoints which receive producer messages sent with Push
+	PushTargets []PushTarget
+	// Protocol decides how URLs are built and which server requests are answered automatically
+	Protocol Protocol
+	// Schema validates sent and received bodies, the validation is disabled if it is nil
+	Schema *xsd.Schema
+	// StrictValidation prevents sending requests which do not match the Schema
+	StrictValidation bool
+	// FetchedResponse receives the responses of DataSupplyRequests sent automatically in fetched mode
+	FetchedResponse       <-chan ServerResponse
+	fetchedResponseWriter chan ServerResponse
+	fetchedMode           *atomic.Bool
+	serverRequestWriter   chan ServerRequest
+	startTime             time.Time
+	// mu guards ClientRef, ServerURL, Header, httpclient and pushTransport, they are switched while requests are handled
+	mu         *sync.RWMutex
+	httpclient httputils.LoggingClient
+	// pushTransport trusts the same certificate authorities as the httpclient without presenting its certificate
+	pushTransport http.RoundTripper
+	httpserver    *httputils.LoggingMuxServer
 }
 
 // ClientRequest represents a request sent by the SIRI client to the server
 type ClientRequest struct {
 	URL  string
 	Body string
+	// Header contains additional headers which take precedence over the Header of the Client
+	Header http.Header
+	// Push marks a request to a push target, it is sent without the credentials and headers of the server
+	Push bool
 }
 
 // AutoClientResponse represents the automatic response sent by the client to the SIRI server
 // used for requests such as DataReady requests
-// there is currently only one automatic response for all requests
+// it is used for all requests no AutoResponseRule matches
 type AutoClientResponse struct {
 	Body   string
 	Status int
@@ -40,49 +82,360 @@ type AutoClientResponse struct {
 type ServerResponse struct {
 	Body     string
 	Status   int
+	Header   http.Header
 	Language string
+	// Duration is the round-trip time of the request
+	Duration time.Duration
+	// Size is the size of the Body in bytes
+	Size       int
+	ReceivedAt time.Time
+	// Message is the parsed Body or nil if the Body is not a SIRI message
+	Message *Message
+	// VDVMessage is the parsed Body or nil if the Body is not a VDV453 message
+	VDVMessage *VDVMessage
+	// RequestViolations are the schema violations of the sent request
+	RequestViolations []xsd.Violation
+	// Violations are the schema violations of the Body
+	Violations []xsd.Violation
 }
 
 // ServerRequest represents a request sent by the SIRI server to the client
 type ServerRequest struct {
 	RemoteAddress string
+	Method        string
 	URL           string
+	Header        http.Header
 	Body          string
 	Language      string
+	// Size is the size of the Body in bytes
+	Size       int
+	ReceivedAt time.Time
+	// Message is the parsed Body or nil if the Body is not a SIRI message
+	Message *Message
+	// VDVMessage is the parsed Body or nil if the Body is not a VDV453 message
+	VDVMessage *VDVMessage
+	// Violations are the schema violations of the Body
+	Violations []xsd.Violation
+}
+
+// MessageName returns the name of the SIRI or VDV453 message in the Body
+func (r ServerResponse) MessageName() string {
+	return cmp.Or(r.Message.Name(), r.VDVMessage.Name())
+}
+
+// MessageStatus returns the status reported within the SIRI or VDV453 message and whether one was found
+func (r ServerResponse) MessageStatus() (status bool, found bool) {
+	if status, found := r.Message.Status(); found {
+		return status, true
+	}
+	return r.VDVMessage.Result()
+}
+
+// MessageName returns the name of the SIRI or VDV453 message in the Body
+func (r ServerRequest) MessageName() string {
+	return cmp.Or(r.Message.Name(), r.VDVMessage.Name())
+}
+
+// ValidationError describes the schema violations of a body
+type ValidationError struct {
+	// Subject is the kind of the validated body like request or response
+	Subject    string
+	Violations []xsd.Violation
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Violations) == 0 {
+		return e.Subject + " is valid"
+	}
+	message := fmt.Sprintf("%s is not valid: %s", e.Subject, e.Violations[0])
+	if len(e.Violations) > 1 {
+		message += fmt.Sprintf(" (and %d more)", len(e.Violations)-1)
+	}
+	return message
+}
+
+// maxVDVFetches limits the DatenAbrufenAnfrage sent for one DatenBereitAnfrage
+const maxVDVFetches = 100
+
+// serverSettings are the settings of the server which can be switched with UseServer
+type serverSettings struct {
+	clientRef  string
+	serverURL  string
+	header     http.Header
+	httpclient httputils.LoggingClient
+	// pushTransport is used for pushes to consumers, it is nil if no TLS settings are configured
+	pushTransport http.RoundTripper
 }
 
 // NewClient creates a new Client to interact with a SIRI server
 func NewClient(clientRef string, serverURL string, address string, requestLogging io.Writer) Client {
 	serverRequest := make(chan ServerRequest, 5)
+	fetchedResponse := make(chan ServerResponse, 5)
 	return Client{
-		ClientRef:           clientRef,
-		ServerURL:           serverURL,
-		ServerRequest:       serverRequest,
-		serverRequestWriter: serverRequest,
+		ClientRef:             clientRef,
+		ServerURL:             serverURL,
+		ConsumerAddress:       listenerURL(address),
+		ServerRequest:         serverRequest,
+		serverRequestWriter:   serverRequest,
+		FetchedResponse:       fetchedResponse,
+		fetchedResponseWriter: fetchedResponse,
+		fetchedMode:           &atomic.Bool{},
 		AutoClientResponse: &AutoClientResponse{
 			Body:   "",
 			Status: http.StatusOK,
 		},
-		httpclient: httputils.NewLoggingClient(requestLogging),
-		httpserver: httputils.NewLoggingMuxServer(address, requestLogging),
+		AutoResponseRules: &AutoResponseRules{},
+		Subscriptions:     NewSubscriptions(),
+		History:           NewHistory(),
+		Deliveries:        NewDeliveries(),
+		Heartbeats:        NewHeartbeats(),
+		Protocol:          ProtocolSIRI,
+		startTime:         time.Now(),
+		mu:                &sync.RWMutex{},
+		httpclient:        httputils.NewLoggingClient(requestLogging),
+		httpserver:        httputils.NewLoggingMuxServer(address, requestLogging),
+	}
+}
+
+// listenerURL returns the URL of a listener on the address, addresses without host listen on localhost.
+// It is empty if the client does not listen.
+func listenerURL(address string) string {
+	if address == "" {
+		return ""
+	}
+	if strings.HasPrefix(address, ":") {
+		return "http://localhost" + address
+	}
+	return "http://" + address
+}
+
+// TemplateURL returns the URL a template is sent to. The path comment of the template is appended to the ServerURL.
+// Without a path comment the URL is derived from the VDV453 URL scheme in VDV453 mode.
+func (c *Client) TemplateURL(name string, template string) string {
+	settings := c.settings()
+	if urlPath := GetURLPathFromTemplate(template); urlPath != "" {
+		return settings.serverURL + urlPath
+	}
+	if c.Protocol == ProtocolVDV453 {
+		if url, ok := vdvTemplateURL(settings.serverURL, settings.clientRef, name, template); ok {
+			return url
+		}
+	}
+	return settings.serverURL
+}
+
+// Send sends a message to the SIRI server, the request is aborted when the context is canceled
+func (c *Client) Send(ctx context.Context, clientRequest ClientRequest) (ServerResponse, error) {
+	executedBody, err := executeTemplate(
+		clientRequest.Body,
+		data{ClientRef: c.settings().clientRef, ConsumerAddress: c.ConsumerAddress},
+	)
+	if err != nil {
+		return ServerResponse{}, err
 	}
+	return c.Replay(ctx, ClientRequest{URL: clientRequest.URL, Body: executedBody, Header: clientRequest.Header})
 }
 
-// Send sends a message to the SIRI server
-func (c *Client) Send(clientRequest ClientRequest) (ServerResponse, error) {
-	executedBody, err := executeTemplate(clientRequest.Body, data{ClientRef: c.ClientRef})
+// Replay sends the body to the SIRI server exactly as it is without executing it as template.
+// Push requests are sent to the push target like Push does.
+func (c *Client) Replay(ctx context.Context, clientRequest ClientRequest) (ServerResponse, error) {
+	if clientRequest.Push {
+		return c.push(ctx, clientRequest)
+	}
+	settings := c.settings()
+	header := settings.header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	maps.Copy(header, clientRequest.Header)
+
+	response, err := c.post(ctx, settings.httpclient, ClientRequest{
+		URL:    clientRequest.URL,
+		Body:   clientRequest.Body,
+		Header: header,
+	})
 	if err != nil {
 		return ServerResponse{}, err
 	}
-	res, err := c.httpclient.PostXML(clientRequest.URL, executedBody)
+	c.Subscriptions.track(clientRequest.URL, TryParseMessage(clientRequest.Body), response.Message)
+	return response, nil
+}
+
+// post validates and sends the request with the httpclient and adds the exchange to the History
+func (c *Client) post(
+	ctx context.Context,
+	httpclient httputils.LoggingClient,
+	clientRequest ClientRequest,
+) (ServerResponse, error) {
+	requestViolations := c.validate(clientRequest.Body)
+	if c.StrictValidation && len(requestViolations) > 0 {
+		return ServerResponse{}, &ValidationError{Subject: "request", Violations: requestViolations}
+	}
+
+	start := time.Now()
+	requestHeader := http.Header{httputils.HeaderContentType: {httputils.ContentTypeXML}}
+	maps.Copy(requestHeader, clientRequest.Header)
+	requestHeader = httputils.RedactHeader(requestHeader)
+	exchange := Exchange{
+		Time:            start,
+		Direction:       Outgoing,
+		Method:          http.MethodPost,
+		URL:             clientRequest.URL,
+		RequestHeader:   requestHeader,
+		RequestBody:     clientRequest.Body,
+		RequestLanguage: "xml",
+		Push:            clientRequest.Push,
+	}
+	res, err := httpclient.PostXML(ctx, clientRequest.URL, clientRequest.Body, clientRequest.Header)
+	exchange.Duration = time.Since(start)
 	if err != nil {
+		exchange.Error = err.Error()
+		c.History.Add(exchange)
 		return ServerResponse{}, err
 	}
-	return ServerResponse{
-		Body:     res.Body,
-		Status:   res.StatusCode,
-		Language: httputils.GetLanguage(res.Header),
-	}, nil
+	response := ServerResponse{
+		Body:              res.Body,
+		Status:            res.StatusCode,
+		Header:            res.Header,
+		Language:          httputils.GetLanguage(res.Header),
+		Duration:          exchange.Duration,
+		Size:              len(res.Body),
+		ReceivedAt:        start.Add(exchange.Duration),
+		Message:           TryParseMessage(res.Body),
+		VDVMessage:        ParseVDVMessage(res.Body),
+		RequestViolations: requestViolations,
+		Violations:        c.validate(res.Body),
+	}
+	exchange.RequestHeader = res.RequestHeader
+	exchange.Status = response.Status
+	exchange.ResponseHeader = res.Header
+	exchange.ResponseBody = response.Body
+	exchange.ResponseLanguage = response.Language
+	c.History.Add(exchange)
+	return response, nil
+}
+
+// settings returns a copy of the current server settings
+func (c *Client) settings() serverSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return serverSettings{
+		clientRef:     c.ClientRef,
+		serverURL:     c.ServerURL,
+		header:        c.Header,
+		httpclient:    c.httpclient,
+		pushTransport: c.pushTransport,
+	}
+}
+
+// UseServer switches the client to another server. All settings are switched at once,
+// nothing is changed if the TLS settings cannot be used.
+func (c *Client) UseServer(
+	serverURL string,
+	clientRef string,
+	header http.Header,
+	tlsConfig httputils.TLSConfig,
+	auth httputils.Authenticator,
+) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	httpclient := c.httpclient
+	pushTransport, err := setTLS(&httpclient, tlsConfig)
+	if err != nil {
+		return err
+	}
+	httpclient.SetAuthenticator(auth)
+	c.ServerURL = serverURL
+	c.ClientRef = clientRef
+	c.Header = header
+	c.httpclient = httpclient
+	c.pushTransport = pushTransport
+	return nil
+}
+
+// SetTLS configures how HTTPS connections to the server and to push targets are established
+func (c *Client) SetTLS(config httputils.TLSConfig) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pushTransport, err := setTLS(&c.httpclient, config)
+	if err != nil {
+		return err
+	}
+	c.pushTransport = pushTransport
+	return nil
+}
+
+// setTLS configures the httpclient and returns the transport for pushes. It trusts the same certificate authorities
+// but does not present the client certificate, since it belongs to the server connection.
+func setTLS(httpclient *httputils.LoggingClient, config httputils.TLSConfig) (http.RoundTripper, error) {
+	if config.IsZero() {
+		httpclient.SetTLSConfig(nil)
+		return nil, nil
+	}
+	tlsConfig, err := config.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	httpclient.SetTLSConfig(tlsConfig)
+	pushTLSConfig := tlsConfig.Clone()
+	pushTLSConfig.Certificates = nil
+	return httputils.NewTransport(pushTLSConfig), nil
+}
+
+// SetTimeout sets the time a request to the server including reading the response may take, 0 means no timeout
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.httpclient.SetTimeout(timeout)
+}
+
+// SetRetryPolicy sets how often requests are sent again after connection errors and 5xx responses
+func (c *Client) SetRetryPolicy(policy httputils.RetryPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.httpclient.SetRetryPolicy(policy)
+}
+
+// SetCompressRequests sets whether request bodies are compressed with gzip
+func (c *Client) SetCompressRequests(compress bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.httpclient.SetCompressRequests(compress)
+}
+
+// SetListenerTimeout sets the time the server may take to send the headers of its requests.
+// Must be called before ListenAndServe.
+func (c *Client) SetListenerTimeout(timeout time.Duration) {
+	c.httpserver.ReadHeaderTimeout = timeout
+}
+
+// SetAuthenticator sets how requests to the server are authenticated, nil disables the authentication
+func (c *Client) SetAuthenticator(auth httputils.Authenticator) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.httpclient.SetAuthenticator(auth)
+}
+
+// SetListenerTLS lets the client listen for HTTPS instead of HTTP server requests.
+// Must be called before ListenAndServe.
+func (c *Client) SetListenerTLS(config httputils.ServerTLSConfig) error {
+	tlsConfig, err := config.Config()
+	if err != nil {
+		return err
+	}
+	c.httpserver.TLSConfig = tlsConfig
+	return nil
+}
+
+// SetFetchedMode enables or disables the fetched mode.
+// In fetched mode the client automatically sends a DataSupplyRequest after acknowledging a DataReadyNotification.
+func (c *Client) SetFetchedMode(enabled bool) {
+	c.fetchedMode.Store(enabled)
+}
+
+// FetchedMode reports whether the fetched mode is enabled
+func (c *Client) FetchedMode() bool {
+	return c.fetchedMode.Load()
 }
 
 // ListenAndServe starts the HTTP server needed to listen for SIRI server requests such as DataReady requests
@@ -103,15 +456,29 @@ func (c *Client) Stop(ctx context.Context) error {
 }
 
 func (c *Client) handleServerRequests(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
 	bytesBody, err := io.ReadAll(r.Body)
 	if err != nil {
 		request := ServerRequest{
 			RemoteAddress: r.RemoteAddr,
+			Method:        r.Method,
 			URL:           r.URL.RequestURI(),
+			Header:        r.Header,
 			Body:          err.Error(),
 			Language:      "plaintext",
+			ReceivedAt:    start,
 		}
 		c.serverRequestWriter <- request
+		c.History.Add(Exchange{
+			Time:          start,
+			Direction:     Incoming,
+			Method:        r.Method,
+			RemoteAddress: request.RemoteAddress,
+			URL:           request.URL,
+			RequestHeader: request.Header,
+			Duration:      time.Since(start),
+			Error:         err.Error(),
+		})
 		slog.Error("Could not read request body", slog.Any("error", err))
 		http.Error(w, "Could not read request body", http.StatusInternalServerError)
 		return
@@ -119,23 +486,189 @@ func (c *Client) handleServerRequests(w http.ResponseWriter, r *http.Request) {
 
 	request := ServerRequest{
 		RemoteAddress: r.RemoteAddr,
+		Method:        r.Method,
 		URL:           r.URL.RequestURI(),
+		Header:        r.Header,
 		Body:          string(bytesBody),
 		Language:      httputils.GetLanguage(r.Header),
+		Size:          len(bytesBody),
+		ReceivedAt:    start,
+		Message:       TryParseMessage(string(bytesBody)),
+		VDVMessage:    ParseVDVMessage(string(bytesBody)),
+		Violations:    c.validate(string(bytesBody)),
 	}
 
 	c.serverRequestWriter <- request
 
-	responseBody, err := executeTemplate(
-		c.AutoClientResponse.Body,
-		data{ClientRef: c.ClientRef},
-	)
-	if err != nil {
-		slog.Error("Could not execute template for autoresponse", slog.Any("error", err))
-		http.Error(w, "Could not execute template for autoresponse", http.StatusInternalServerError)
-		return
+	if request.Message != nil && request.Message.HeartbeatNotification != nil {
+		c.Heartbeats.receive(request.Message.HeartbeatNotification.ProducerRef, request.ReceivedAt)
+	}
+
+	// deliveries are always recorded, even if an auto-response rule answers them
+	var deliveryAcknowledgement *AutoClientResponse
+	if request.Message != nil && request.Message.ServiceDelivery != nil {
+		if deliveryAcknowledgement, err = c.receiveDelivery(request); err != nil {
+			slog.Error("Could not create DataReceivedAcknowledgement", slog.Any("error", err))
+		}
+	}
+
+	autoResponse := *c.AutoClientResponse
+	// generated acknowledgements contain refs of the request, they are sent as they are and not executed as template
+	generated := false
+	if rule, ok := c.AutoResponseRules.match(r.URL.Path, request.Body); ok {
+		autoResponse = AutoClientResponse{Body: rule.Body, Status: rule.Status}
+	} else if deliveryAcknowledgement != nil {
+		autoResponse = *deliveryAcknowledgement
+		generated = true
+	} else if c.Protocol == ProtocolVDV453 {
+		autoResponse, generated = c.vdvAutoResponse(request.VDVMessage, autoResponse)
+	}
+
+	responseBody := autoResponse.Body
+	if !generated {
+		responseBody, err = executeTemplate(autoResponse.Body, data{ClientRef: c.settings().clientRef})
+		if err != nil {
+			slog.Error("Could not execute template for autoresponse", slog.Any("error", err))
+			http.Error(w, "Could not execute template for autoresponse", http.StatusInternalServerError)
+			return
+		}
 	}
 	w.Header().Set(httputils.HeaderContentType, httputils.ContentTypeXML)
-	w.WriteHeader(c.AutoClientResponse.Status)
+	w.WriteHeader(autoResponse.Status)
 	fmt.Fprint(w, responseBody)
+	c.History.Add(Exchange{
+		Time:             start,
+		Direction:        Incoming,
+		Method:           r.Method,
+		RemoteAddress:    request.RemoteAddress,
+		URL:              request.URL,
+		RequestHeader:    request.Header,
+		RequestBody:      request.Body,
+		RequestLanguage:  request.Language,
+		Status:           autoResponse.Status,
+		ResponseHeader:   w.Header().Clone(),
+		ResponseBody:     responseBody,
+		ResponseLanguage: "xml",
+		Duration:         time.Since(start),
+	})
+
+	if !c.FetchedMode() {
+		return
+	}
+	// async so the acknowledgement is sent before the data is fetched
+	switch {
+	case request.Message != nil && request.Message.DataReadyNotification != nil:
+		go c.fetchData()
+	case request.VDVMessage.Name() == "DatenBereitAnfrage":
+		if vdvPath, ok := ParseVDVPath(r.URL.Path); ok {
+			go c.fetchVDVData(vdvPath.Service)
+		}
+	}
+}
+
+// vdvAutoResponse acknowledges DatenBereitAnfrage and ClientStatusAnfrage, all other requests get the fallback.
+// The flag reports whether the acknowledgement was generated instead of using the fallback.
+func (c *Client) vdvAutoResponse(request *VDVMessage, fallback AutoClientResponse) (AutoClientResponse, bool) {
+	acknowledgement, ok := vdvAcknowledgement(request, time.Now(), c.startTime)
+	if !ok {
+		return fallback, false
+	}
+	body, err := acknowledgement.Marshal()
+	if err != nil {
+		slog.Error("Could not create VDV453 acknowledgement", slog.Any("error", err))
+		return fallback, false
+	}
+	return AutoClientResponse{Body: body, Status: http.StatusOK}, true
+}
+
+// sendGenerated sends a body the client created itself with the Header of the client.
+// Unlike Send the body is not executed as template, since it may contain refs of the server.
+func (c *Client) sendGenerated(ctx context.Context, url string, body string) (ServerResponse, error) {
+	settings := c.settings()
+	return c.post(ctx, settings.httpclient, ClientRequest{URL: url, Body: body, Header: settings.header.Clone()})
+}
+
+// fetchData sends a DataSupplyRequest to every service URL with a subscription
+func (c *Client) fetchData() {
+	body, err := Message{
+		DataSupplyRequest: &DataSupplyRequest{
+			RequestTimestamp: DateTime{Time: time.Now().UTC()},
+			ConsumerRef:      c.settings().clientRef,
+		},
+	}.Marshal()
+	if err != nil {
+		slog.Error("Could not create DataSupplyRequest", slog.Any("error", err))
+		return
+	}
+
+	for _, url := range c.dataSupplyURLs() {
+		response, err := c.sendGenerated(context.Background(), url, body)
+		if err != nil {
+			slog.Error("Could not fetch data", slog.String("url", url), slog.Any("error", err))
+			response = ServerResponse{Body: err.Error(), Language: "plaintext"}
+		}
+		c.fetchedResponseWriter <- response
+	}
+}
+
+// fetchVDVData sends DatenAbrufenAnfrage for the service until the server has no WeitereDaten
+func (c *Client) fetchVDVData(service string) {
+	settings := c.settings()
+	url := VDVURL(settings.serverURL, settings.clientRef, service, VDVFetchData)
+	allData := false
+	for range maxVDVFetches {
+		body, err := VDVMessage{
+			XMLName:   xml.Name{Local: "DatenAbrufenAnfrage"},
+			Sender:    settings.clientRef,
+			Timestamp: vdvTimestamp(time.Now()),
+			AllData:   &allData,
+		}.Marshal()
+		if err != nil {
+			slog.Error("Could not create DatenAbrufenAnfrage", slog.Any("error", err))
+			return
+		}
+		response, err := c.sendGenerated(context.Background(), url, body)
+		if err != nil {
+			slog.Error("Could not fetch data", slog.String("url", url), slog.Any("error", err))
+			c.fetchedResponseWriter <- ServerResponse{Body: err.Error(), Language: "plaintext"}
+			return
+		}
+		c.fetchedResponseWriter <- response
+		if response.VDVMessage == nil || response.VDVMessage.MoreData == nil || !*response.VDVMessage.MoreData {
+			return
+		}
+	}
+	slog.Warn("Stopped fetching data, the server still reports WeitereDaten", slog.String("url", url))
+}
+
+// dataSupplyURLs returns the URLs of all subscriptions which are not terminated or rejected.
+// The ServerURL is used if there are none.
+func (c *Client) dataSupplyURLs() []string {
+	var urls []string
+	for _, subscription := range c.Subscriptions.List() {
+		if subscription.Status != SubscriptionRequested && subscription.Status != SubscriptionActive {
+			continue
+		}
+		if !slices.Contains(urls, subscription.URL) {
+			urls = append(urls, subscription.URL)
+		}
+	}
+	if len(urls) == 0 {
+		serverURL := c.settings().serverURL
+		slog.Warn("No subscription found for DataReadyNotification, using server URL", slog.String("url", serverURL))
+		return []string{serverURL}
+	}
+	return urls
+}
+
+// validate returns the schema violations of the body, a body which is no XML is a violation too
+func (c *Client) validate(body string) []xsd.Violation {
+	if c.Schema == nil || strings.TrimSpace(body) == "" {
+		return nil
+	}
+	violations, err := c.Schema.Validate(body)
+	if err != nil {
+		return []xsd.Violation{{Line: 1, Path: "/", Message: "not well-formed XML: " + err.Error()}}
+	}
+	return violations
 }
@@ -1,13 +1,19 @@
 package siri //nolint testpackage
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/mszalbach/sirigo/internal/httputils"
+	"github.com/mszalbach/sirigo/internal/xsd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -37,7 +43,7 @@ func Test_siri_client_sending_to_server(t *testing.T) {
 
 	// When
 	client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
-	actual, err := client.Send(ClientRequest{
+	actual, err := client.Send(t.Context(), ClientRequest{
 		URL: server.URL + "/siri/2.1/situation-exchange",
 		Body: `
 <Siri>
@@ -74,6 +80,17 @@ func Test_siri_client_sending_to_server(t *testing.T) {
 		Language: "xml",
 		Status:   http.StatusOK,
 	}
+	require.NotNil(t, actual.Message)
+	require.NotNil(t, actual.Message.SubscriptionResponse)
+	assert.Equal(t, "0003456", actual.Message.SubscriptionResponse.ResponseStatus[0].SubscriptionRef)
+	assert.True(t, actual.Message.SubscriptionResponse.ResponseStatus[0].Status)
+	assert.Equal(t, "application/xml", actual.Header.Get("Content-Type"))
+	assert.Equal(t, len(expected.Body), actual.Size)
+	assert.Positive(t, actual.Duration)
+	assert.WithinDuration(t, time.Now(), actual.ReceivedAt, time.Minute)
+	// the parsed message and the metadata are checked above
+	actual.Message = nil
+	actual.Header, actual.Size, actual.Duration, actual.ReceivedAt = nil, 0, 0, time.Time{}
 	assert.Equal(t, expected, actual)
 }
 
@@ -104,7 +121,7 @@ func Test_siri_client_body_is_a_template(t *testing.T) {
 
 	// When
 	client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
-	_, err := client.Send(ClientRequest{
+	_, err := client.Send(t.Context(), ClientRequest{
 		URL: server.URL + "/siri/2.1/situation-exchange",
 		Body: `
 <Siri>
@@ -168,7 +185,9 @@ func Test_siri_client_receiving_from_server(t *testing.T) {
 
 	expectedServerRequest := ServerRequest{
 		RemoteAddress: "196.4.4.1",
+		Method:        http.MethodPost,
 		URL:           "/siri",
+		Header:        http.Header{"Content-Type": {"application/xml"}},
 		Language:      "xml",
 		Body: `
 <Siri>
@@ -179,6 +198,14 @@ func Test_siri_client_receiving_from_server(t *testing.T) {
 </Siri>`,
 	}
 
+	require.NotNil(t, actualServerRequest.Message)
+	require.NotNil(t, actualServerRequest.Message.DataReadyNotification)
+	assert.Equal(t, "KUBRICK", actualServerRequest.Message.DataReadyNotification.ProducerRef)
+	assert.Equal(t, len(expectedServerRequest.Body), actualServerRequest.Size)
+	assert.WithinDuration(t, time.Now(), actualServerRequest.ReceivedAt, time.Minute)
+	// the parsed message and the metadata are checked above
+	actualServerRequest.Message = nil
+	actualServerRequest.Size, actualServerRequest.ReceivedAt = 0, time.Time{}
 	assert.Equal(t, expectedServerRequest, actualServerRequest)
 }
 
@@ -205,15 +232,15 @@ func Test_client_send_understands_content_types(t *testing.T) {
 
 			// When
 			client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
-			actual, err := client.Send(ClientRequest{
+			actual, err := client.Send(t.Context(), ClientRequest{
 				URL:  server.URL + "/siri/v2",
 				Body: "IGNORE",
 			})
 			require.NoError(t, err)
 
 			// Then
-			expected := ServerResponse{Body: ``, Language: tc.expectedLanguage, Status: http.StatusOK}
-			assert.Equal(t, expected, actual)
+			assert.Equal(t, tc.expectedLanguage, actual.Language)
+			assert.Equal(t, tc.actualContentType, actual.Header.Get("Content-Type"))
 		})
 	}
 }
@@ -242,3 +269,375 @@ func Test_server_does_not_work_for_non_post(t *testing.T) {
 		})
 	}
 }
+
+func Test_siri_client_tracks_sent_subscriptions(t *testing.T) {
+	// Given
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.Header().Set("Content-Type", "application/xml")
+		rw.WriteHeader(http.StatusOK)
+		fmt.Fprint(rw, `
+<Siri>
+	<SubscriptionResponse>
+		<ResponseTimestamp>2004-12-17T09:30:47-05:00</ResponseTimestamp>
+		<ResponseStatus>
+			<ResponseTimestamp>2004-12-17T09:30:47-05:00</ResponseTimestamp>
+			<SubscriptionRef>1</SubscriptionRef>
+			<Status>true</Status>
+		</ResponseStatus>
+	</SubscriptionResponse>
+</Siri>`)
+	}))
+	defer server.Close()
+
+	// When
+	client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+	_, err := client.Send(t.Context(), ClientRequest{
+		URL: server.URL + "/siri/et",
+		Body: `
+<Siri>
+	<SubscriptionRequest>
+		<RequestTimestamp>{{ dateTime .Now }}</RequestTimestamp>
+		<RequestorRef>{{ .ClientRef }}</RequestorRef>
+		<EstimatedTimetableSubscriptionRequest>
+			<SubscriptionIdentifier>1</SubscriptionIdentifier>
+			<InitialTerminationTime>{{ dateTime (addTime .Now "2h") }}</InitialTerminationTime>
+		</EstimatedTimetableSubscriptionRequest>
+	</SubscriptionRequest>
+</Siri>`,
+	})
+	require.NoError(t, err)
+
+	// Then
+	actual := client.Subscriptions.List()
+	require.Len(t, actual, 1)
+	assert.Equal(t, "CLIENT REF", actual[0].SubscriberRef)
+	assert.Equal(t, server.URL+"/siri/et", actual[0].URL)
+	assert.Equal(t, SubscriptionActive, actual[0].Status)
+}
+
+func Test_siri_client_fetches_data_in_fetched_mode(t *testing.T) {
+	// Given
+	dataSupplyRequests := make(chan *Message, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		bytesBody, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		message, err := ParseMessage(string(bytesBody))
+		assert.NoError(t, err)
+		if message.SubscriptionRequest == nil {
+			dataSupplyRequests <- message
+		}
+		rw.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(rw, `<Siri><ServiceDelivery><ProducerRef>KUBRICK</ProducerRef></ServiceDelivery></Siri>`)
+	}))
+	defer server.Close()
+
+	// the generated DataSupplyRequest is not executed as template, so refs may contain template actions
+	client := NewClient("{{ CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+	client.SetFetchedMode(true)
+	_, err := client.Send(t.Context(), ClientRequest{
+		URL: server.URL + "/siri/et",
+		Body: `<Siri><SubscriptionRequest><EstimatedTimetableSubscriptionRequest>
+			<SubscriptionIdentifier>1</SubscriptionIdentifier>
+		</EstimatedTimetableSubscriptionRequest></SubscriptionRequest></Siri>`,
+	})
+	require.NoError(t, err)
+
+	// When
+	serverRequest, _ := http.NewRequest(http.MethodPost, "/siri", strings.NewReader(`
+<Siri>
+	<DataReadyNotification>
+		<RequestTimestamp>2004-12-17T09:30:47-05:00</RequestTimestamp>
+		<ProducerRef>KUBRICK</ProducerRef>
+	</DataReadyNotification>
+</Siri>`))
+	response := httptest.NewRecorder()
+	client.createHandler().ServeHTTP(response, serverRequest)
+
+	// Then
+	assert.Equal(t, http.StatusOK, response.Result().StatusCode)
+	select {
+	case actualRequest := <-dataSupplyRequests:
+		require.NotNil(t, actualRequest.DataSupplyRequest)
+		assert.Equal(t, "{{ CLIENT REF", actualRequest.DataSupplyRequest.ConsumerRef)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "no DataSupplyRequest received")
+	}
+	select {
+	case actualResponse := <-client.FetchedResponse:
+		assert.Equal(t, "ServiceDelivery", actualResponse.Message.Name())
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "no fetched response received")
+	}
+}
+
+func Test_siri_client_does_not_fetch_data_without_fetched_mode(t *testing.T) {
+	// Given
+	client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+
+	// When
+	serverRequest, _ := http.NewRequest(http.MethodPost, "/siri", strings.NewReader(`
+<Siri>
+	<DataReadyNotification>
+		<ProducerRef>KUBRICK</ProducerRef>
+	</DataReadyNotification>
+</Siri>`))
+	client.createHandler().ServeHTTP(httptest.NewRecorder(), serverRequest)
+
+	// Then
+	assert.False(t, client.FetchedMode())
+	assert.Empty(t, client.FetchedResponse)
+}
+
+func Test_siri_client_uses_matching_auto_response_rule(t *testing.T) {
+	// Given
+	client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+	client.AutoClientResponse.Body = "<Siri><DataReadyAcknowledgement/></Siri>"
+	client.AutoResponseRules.SetRules([]AutoResponseRule{
+		{
+			RequestMatcher: RequestMatcher{Element: "CheckStatusRequest"},
+			Body:           "<Siri><CheckStatusResponse><ProducerRef>{{ .ClientRef }}</ProducerRef></CheckStatusResponse></Siri>",
+			Status:         http.StatusAccepted,
+		},
+	})
+
+	// When
+	serverRequest, _ := http.NewRequest(http.MethodPost, "/siri", strings.NewReader(
+		"<Siri><CheckStatusRequest><RequestorRef>KUBRICK</RequestorRef></CheckStatusRequest></Siri>",
+	))
+	response := httptest.NewRecorder()
+	client.createHandler().ServeHTTP(response, serverRequest)
+
+	// Then
+	assert.Equal(t, http.StatusAccepted, response.Result().StatusCode)
+	assert.Equal(
+		t,
+		"<Siri><CheckStatusResponse><ProducerRef>CLIENT REF</ProducerRef></CheckStatusResponse></Siri>",
+		response.Body.String(),
+	)
+}
+
+func Test_siri_client_records_history(t *testing.T) {
+	// Given
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.Header().Set("Content-Type", "application/xml")
+		rw.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(rw, "<Siri><CheckStatusResponse/></Siri>")
+	}))
+	defer server.Close()
+	client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+	client.AutoClientResponse.Body = "<Siri><DataReadyAcknowledgement/></Siri>"
+
+	// When
+	_, err := client.Send(t.Context(), ClientRequest{
+		URL:  server.URL + "/status",
+		Body: "<Siri><CheckStatusRequest>{{ .ClientRef }}</CheckStatusRequest></Siri>",
+	})
+	require.NoError(t, err)
+	serverRequest, _ := http.NewRequest(
+		http.MethodPost,
+		"/siri",
+		strings.NewReader("<Siri><DataReadyNotification/></Siri>"),
+	)
+	serverRequest.RemoteAddr = "196.4.4.1"
+	client.createHandler().ServeHTTP(httptest.NewRecorder(), serverRequest)
+
+	// Then
+	exchanges := client.History.List()
+	require.Len(t, exchanges, 2)
+	assert.Equal(t, Outgoing, exchanges[0].Direction)
+	assert.Equal(t, server.URL+"/status", exchanges[0].URL)
+	assert.Equal(t, "<Siri><CheckStatusRequest>CLIENT REF</CheckStatusRequest></Siri>", exchanges[0].RequestBody)
+	assert.Equal(t, "<Siri><CheckStatusResponse/></Siri>", exchanges[0].ResponseBody)
+	assert.Equal(t, http.StatusAccepted, exchanges[0].Status)
+	assert.Equal(t, Incoming, exchanges[1].Direction)
+	assert.Equal(t, "196.4.4.1", exchanges[1].RemoteAddress)
+	assert.Equal(t, "DataReadyNotification", exchanges[1].Name())
+	assert.Equal(t, "<Siri><DataReadyAcknowledgement/></Siri>", exchanges[1].ResponseBody)
+	assert.Equal(t, http.StatusOK, exchanges[1].Status)
+}
+
+func Test_siri_client_replays_body_without_executing_it(t *testing.T) {
+	// Given
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		received = string(body)
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+
+	// When
+	_, err := client.Replay(t.Context(), ClientRequest{URL: server.URL, Body: "<Siri>{{ .ClientRef }}</Siri>"})
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, "<Siri>{{ .ClientRef }}</Siri>", received)
+}
+
+func Test_siri_client_records_failed_requests(t *testing.T) {
+	// Given
+	client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+
+	// When
+	_, err := client.Send(t.Context(), ClientRequest{URL: "http://localhost:1/siri", Body: "<Siri/>"})
+
+	// Then
+	require.Error(t, err)
+	exchanges := client.History.List()
+	require.Len(t, exchanges, 1)
+	assert.Equal(t, err.Error(), exchanges[0].Error)
+	assert.Empty(t, exchanges[0].ResponseBody)
+}
+
+const checkStatusSchema = `<xsd:schema xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+	<xsd:element name="Siri">
+		<xsd:complexType>
+			<xsd:choice>
+				<xsd:element name="CheckStatusRequest" type="xsd:string"/>
+				<xsd:element name="CheckStatusResponse" type="xsd:string"/>
+			</xsd:choice>
+		</xsd:complexType>
+	</xsd:element>
+</xsd:schema>`
+
+func loadCheckStatusSchema(t *testing.T) *xsd.Schema {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "siri.xsd"), []byte(checkStatusSchema), 0o600))
+	schema, err := xsd.Load(dir)
+	require.NoError(t, err)
+	return schema
+}
+
+func Test_siri_client_reports_schema_violations(t *testing.T) {
+	// Given
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(rw, "<Siri><CheckStatusResponse/></Siri>")
+	}))
+	defer server.Close()
+	client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+	client.Schema = loadCheckStatusSchema(t)
+
+	// When
+	response, err := client.Send(t.Context(), ClientRequest{URL: server.URL, Body: "<Siri><CheckStatusRequests/></Siri>"})
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, response.RequestViolations, 2)
+	assert.Equal(t, `unknown element "CheckStatusRequests" in "Siri"`, response.RequestViolations[0].Message)
+	assert.Empty(t, response.Violations)
+}
+
+func Test_siri_client_does_not_send_invalid_requests_in_strict_mode(t *testing.T) {
+	// Given
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		assert.Fail(t, "invalid request must not be sent")
+	}))
+	defer server.Close()
+	client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+	client.Schema = loadCheckStatusSchema(t)
+	client.StrictValidation = true
+
+	// When
+	_, err := client.Send(t.Context(), ClientRequest{URL: server.URL, Body: "<Siri><CheckStatusRequests/></Siri>"})
+
+	// Then
+	var validationError *ValidationError
+	require.ErrorAs(t, err, &validationError)
+	assert.Len(t, validationError.Violations, 2)
+	assert.Equal(
+		t,
+		`request is not valid: line 1 /Siri/CheckStatusRequests: unknown element "CheckStatusRequests" in "Siri" (and 1 more)`,
+		err.Error(),
+	)
+}
+
+func Test_siri_client_validates_server_requests(t *testing.T) {
+	// Given
+	client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+	client.Schema = loadCheckStatusSchema(t)
+
+	// When
+	serverRequest, _ := http.NewRequest(http.MethodPost, "/siri", strings.NewReader("<Siri><DataReady/></Siri>"))
+	client.createHandler().ServeHTTP(httptest.NewRecorder(), serverRequest)
+
+	// Then
+	require.Len(t, client.ServerRequest, 1)
+	actual := <-client.ServerRequest
+	require.Len(t, actual.Violations, 2)
+	assert.Equal(t, `unknown element "DataReady" in "Siri"`, actual.Violations[0].Message)
+}
+
+func Test_siri_client_sends_additional_headers(t *testing.T) {
+	// Given
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "partner", req.Header.Get("X-Api-Key"))
+		assert.Equal(t, "text/xml", req.Header.Get("Content-Type"))
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := NewClient("client", server.URL, "CLIENT ADDRESS", io.Discard)
+	client.Header = http.Header{"X-Api-Key": {"client"}}
+
+	// When
+	_, err := client.Send(t.Context(), ClientRequest{
+		URL:    server.URL,
+		Body:   "<Siri/>",
+		Header: http.Header{"X-Api-Key": {"partner"}, "Content-Type": {"text/xml"}},
+	})
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, "[redacted]", client.History.List()[0].RequestHeader.Get("X-Api-Key"), "credentials are not recorded")
+	assert.Equal(t, http.Header{"X-Api-Key": {"client"}}, client.Header)
+}
+
+func Test_siri_client_switches_server_while_sending(t *testing.T) {
+	// Given
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := NewClient("client", server.URL, "CLIENT ADDRESS", io.Discard)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 10 {
+			_, err := client.Send(t.Context(), ClientRequest{URL: client.TemplateURL("check", "<Siri/>"), Body: "<Siri/>"})
+			assert.NoError(t, err)
+		}
+	}()
+
+	// When
+	err := client.UseServer(server.URL, "partner", http.Header{"X-Api-Key": {"partner"}}, httputils.TLSConfig{}, nil)
+	<-done
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, "partner", client.ClientRef)
+	assert.Equal(t, server.URL, client.TemplateURL("check", "<Siri/>"))
+}
+
+func Test_siri_client_request_can_be_canceled(t *testing.T) {
+	// Given
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		<-release
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	defer close(release)
+	client := NewClient("client", server.URL, "CLIENT ADDRESS", io.Discard)
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	// When
+	_, err := client.Send(ctx, ClientRequest{URL: server.URL, Body: "<Siri/>"})
+
+	// Then
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, client.History.List()[0].Error, "context canceled")
+}
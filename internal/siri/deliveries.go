@@ -0,0 +1,130 @@
+package siri
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// deliveriesLimit is the maximum number of received deliveries kept, older ones are dropped
+	deliveriesLimit = 1000
+	// deliveriesSizeLimit is the maximum size of the request bodies kept in bytes, older deliveries are dropped.
+	// The body is counted for every delivery of a ServiceDelivery and the latest delivery is always kept.
+	deliveriesSizeLimit = 64 << 20
+)
+
+// DirectDelivery is the delivery of one service within a ServiceDelivery the server sent in direct delivery mode
+type DirectDelivery struct {
+	ReceivedAt      time.Time
+	ProducerRef     string
+	ServiceType     string
+	SubscriberRef   string
+	SubscriptionRef string
+	// Subscribed is false if the delivery does not belong to a requested or active subscription
+	Subscribed bool
+	// Request is the server request containing the whole ServiceDelivery
+	Request ServerRequest
+}
+
+// Deliveries keeps the latest directly delivered ServiceDeliveries in the order they were received
+type Deliveries struct {
+	// Changed receives a value whenever a delivery was added
+	Changed       <-chan struct{}
+	changedWriter chan struct{}
+	mu            sync.Mutex
+	deliveries    []DirectDelivery
+	// size is the size of the request bodies of all deliveries in bytes
+	size int
+}
+
+// NewDeliveries creates an empty list of deliveries
+func NewDeliveries() *Deliveries {
+	changed := make(chan struct{}, 1)
+	return &Deliveries{
+		Changed:       changed,
+		changedWriter: changed,
+	}
+}
+
+// List returns a copy of all deliveries, the oldest first
+func (d *Deliveries) List() []DirectDelivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Clone(d.deliveries)
+}
+
+func (d *Deliveries) add(deliveries ...DirectDelivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deliveries = append(d.deliveries, deliveries...)
+	for _, delivery := range deliveries {
+		d.size += len(delivery.Request.Body)
+	}
+	dropped := 0
+	for len(d.deliveries)-dropped > 1 && (len(d.deliveries)-dropped > deliveriesLimit || d.size > deliveriesSizeLimit) {
+		d.size -= len(d.deliveries[dropped].Request.Body)
+		dropped++
+	}
+	d.deliveries = slices.Delete(d.deliveries, 0, dropped)
+	select {
+	case d.changedWriter <- struct{}{}:
+	default:
+	}
+}
+
+// receiveDelivery attaches the deliveries of a ServiceDelivery request to the tracked subscriptions
+// and returns the DataReceivedAcknowledgement. The acknowledgement fails if a delivery belongs to a terminated
+// subscription. It is nil if no delivery belongs to a tracked subscription, for example because the
+// subscriptions were not requested by this client, then the default autoresponse is used.
+func (c *Client) receiveDelivery(request ServerRequest) (*AutoClientResponse, error) {
+	serviceDelivery := request.Message.ServiceDelivery
+	deliveries := make([]DirectDelivery, 0, len(serviceDelivery.Deliveries))
+	var terminated []string
+	tracked := false
+	for _, delivery := range serviceDelivery.Deliveries {
+		service, found := strings.CutSuffix(delivery.XMLName.Local, "Delivery")
+		if !found {
+			continue
+		}
+		subscribed, ended := c.Subscriptions.deliver(delivery.SubscriberRef, delivery.SubscriptionRef, request.ReceivedAt)
+		tracked = tracked || subscribed || ended
+		if ended {
+			terminated = append(terminated, delivery.SubscriptionRef)
+		}
+		deliveries = append(deliveries, DirectDelivery{
+			ReceivedAt:      request.ReceivedAt,
+			ProducerRef:     serviceDelivery.ProducerRef,
+			ServiceType:     serviceType(service),
+			SubscriberRef:   delivery.SubscriberRef,
+			SubscriptionRef: delivery.SubscriptionRef,
+			Subscribed:      subscribed,
+			Request:         request,
+		})
+	}
+	c.Deliveries.add(deliveries...)
+	if !tracked {
+		return nil, nil
+	}
+
+	acknowledgement := &DataReceivedAcknowledgement{
+		ResponseTimestamp: DateTime{Time: time.Now().UTC()},
+		ConsumerRef:       c.settings().clientRef,
+		RequestMessageRef: serviceDelivery.RequestMessageRef,
+		Status:            len(terminated) == 0,
+	}
+	if len(terminated) > 0 {
+		acknowledgement.ErrorCondition = errorCondition(
+			"UnknownSubscriptionError",
+			fmt.Sprintf("terminated subscription %s", strings.Join(terminated, ", ")),
+		)
+	}
+	body, err := Message{DataReceivedAcknowledgement: acknowledgement}.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return &AutoClientResponse{Body: body, Status: http.StatusOK}, nil
+}
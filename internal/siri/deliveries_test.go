@@ -0,0 +1,161 @@
+package siri
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func directDelivery(subscriptionRefs ...string) string {
+	var deliveries strings.Builder
+	for _, subscriptionRef := range subscriptionRefs {
+		deliveries.WriteString(`<EstimatedTimetableDelivery>
+			<SubscriberRef>client</SubscriberRef>
+			<SubscriptionRef>` + subscriptionRef + `</SubscriptionRef>
+		</EstimatedTimetableDelivery>`)
+	}
+	return `<Siri><ServiceDelivery>
+		<ProducerRef>producer</ProducerRef>
+		<RequestMessageRef>message</RequestMessageRef>
+		` + deliveries.String() + `
+	</ServiceDelivery></Siri>`
+}
+
+func Test_deliveries_drop_oldest_deliveries_above_size_limit(t *testing.T) {
+	// Given
+	deliveries := NewDeliveries()
+	request := ServerRequest{Body: strings.Repeat("x", deliveriesSizeLimit/2)}
+
+	// When
+	deliveries.add(DirectDelivery{SubscriptionRef: "1", Request: request})
+	deliveries.add(
+		DirectDelivery{SubscriptionRef: "2", Request: request},
+		DirectDelivery{SubscriptionRef: "3", Request: request},
+	)
+
+	// Then
+	actual := deliveries.List()
+	require.Len(t, actual, 2)
+	assert.Equal(t, "2", actual[0].SubscriptionRef)
+	assert.Equal(t, "3", actual[1].SubscriptionRef)
+}
+
+func Test_siri_client_acknowledges_direct_deliveries(t *testing.T) {
+	testCases := map[string]struct {
+		subscriptionRefs   []string
+		expectedStatus     bool
+		expectedSubscribed []bool
+		expectedError      string
+		expectedDelivered  int
+	}{
+		"subscribed": {[]string{"1"}, true, []bool{true}, "", 1},
+		"untracked":  {[]string{"1", "3"}, true, []bool{true, false}, "", 1},
+		"terminated": {[]string{"1", "9"}, false, []bool{true, false}, "terminated subscription 9", 1},
+		"template in ref": {
+			[]string{"{{ .Broken"},
+			false,
+			[]bool{false},
+			"terminated subscription {{ .Broken",
+			0,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+			client.Subscriptions.track("SERVER URL", mustParse(t, etSubscriptionRequest), nil)
+			for _, identifier := range []string{"9", "{{ .Broken"} {
+				client.Subscriptions.put(Subscription{
+					Identifier:    identifier,
+					SubscriberRef: "client",
+					Status:        SubscriptionTerminated,
+				})
+			}
+			request, _ := http.NewRequest(http.MethodPost, "/siri", strings.NewReader(directDelivery(tc.subscriptionRefs...)))
+			response := httptest.NewRecorder()
+
+			// When
+			client.createHandler().ServeHTTP(response, request)
+
+			// Then
+			assert.Equal(t, http.StatusOK, response.Code)
+			acknowledgement := mustParse(t, response.Body.String()).DataReceivedAcknowledgement
+			require.NotNil(t, acknowledgement)
+			assert.Equal(t, "CLIENT REF", acknowledgement.ConsumerRef)
+			assert.Equal(t, "message", acknowledgement.RequestMessageRef)
+			assert.Equal(t, tc.expectedStatus, acknowledgement.Status)
+			assert.Equal(t, tc.expectedError, acknowledgement.ErrorCondition.String())
+
+			deliveries := client.Deliveries.List()
+			require.Len(t, deliveries, len(tc.subscriptionRefs))
+			for i, delivery := range deliveries {
+				assert.Equal(t, "producer", delivery.ProducerRef)
+				assert.Equal(t, "ET", delivery.ServiceType)
+				assert.Equal(t, tc.subscriptionRefs[i], delivery.SubscriptionRef)
+				assert.Equal(t, tc.expectedSubscribed[i], delivery.Subscribed)
+				assert.Equal(t, "/siri", delivery.Request.URL)
+			}
+			assert.Equal(t, tc.expectedDelivered, client.Subscriptions.List()[0].Deliveries)
+		})
+	}
+}
+
+func Test_untracked_direct_deliveries_get_default_auto_response(t *testing.T) {
+	testCases := map[string]struct {
+		subscriptionRefs []string
+		subscriberRef    string
+	}{
+		"headless without subscriptions": {[]string{"1"}, ""},
+		"other subscriber":               {[]string{"2"}, "other"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+			client.AutoClientResponse.Body = "<Siri><DataReceivedAcknowledgement><ConsumerRef>{{ .ClientRef }}</ConsumerRef>" +
+				"<Status>true</Status></DataReceivedAcknowledgement></Siri>"
+			if tc.subscriberRef != "" {
+				client.Subscriptions.put(Subscription{Identifier: "2", SubscriberRef: tc.subscriberRef, Status: SubscriptionActive})
+			}
+			request, _ := http.NewRequest(http.MethodPost, "/siri", strings.NewReader(directDelivery(tc.subscriptionRefs...)))
+			response := httptest.NewRecorder()
+
+			// When
+			client.createHandler().ServeHTTP(response, request)
+
+			// Then
+			assert.Equal(t, http.StatusOK, response.Code)
+			acknowledgement := mustParse(t, response.Body.String()).DataReceivedAcknowledgement
+			require.NotNil(t, acknowledgement)
+			assert.Equal(t, "CLIENT REF", acknowledgement.ConsumerRef)
+			assert.True(t, acknowledgement.Status)
+			require.Len(t, client.Deliveries.List(), 1)
+			assert.False(t, client.Deliveries.List()[0].Subscribed)
+		})
+	}
+}
+
+func Test_auto_response_rules_answer_direct_deliveries(t *testing.T) {
+	// Given
+	client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+	client.AutoResponseRules.SetRules([]AutoResponseRule{
+		{RequestMatcher: RequestMatcher{Element: "ServiceDelivery"}, Body: "<Rule/>", Status: http.StatusAccepted},
+	})
+	request, _ := http.NewRequest(http.MethodPost, "/siri", strings.NewReader(directDelivery("1")))
+	response := httptest.NewRecorder()
+
+	// When
+	client.createHandler().ServeHTTP(response, request)
+
+	// Then
+	assert.Equal(t, http.StatusAccepted, response.Code)
+	assert.Equal(t, "<Rule/>", response.Body.String())
+	require.Len(t, client.Deliveries.List(), 1)
+	assert.WithinDuration(t, time.Now(), client.Deliveries.List()[0].ReceivedAt, time.Minute)
+}
@@ -0,0 +1,254 @@
+package siri
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ProducerHeartbeat is the last HeartbeatNotification received from a producer
+type ProducerHeartbeat struct {
+	ProducerRef   string
+	LastHeartbeat time.Time
+	// Overdue is true if no heartbeat arrived in time since the LastHeartbeat
+	Overdue bool
+}
+
+// HeartbeatOverdueError is raised when a producer did not send a HeartbeatNotification in time
+type HeartbeatOverdueError struct {
+	// ProducerRef is empty if the producer of the subscriptions is not known
+	ProducerRef   string
+	LastHeartbeat time.Time
+	Interval      time.Duration
+	// Missing is true if the producer did not send a heartbeat since the subscriptions were requested.
+	// LastHeartbeat is then the time the first heartbeat was expected from.
+	Missing bool
+}
+
+func (e *HeartbeatOverdueError) Error() string {
+	since := e.LastHeartbeat.Local().Format(time.TimeOnly)
+	switch {
+	case e.Missing && e.ProducerRef == "":
+		return fmt.Sprintf("no HeartbeatNotification received since %s, expected every %s", since, e.Interval)
+	case e.Missing:
+		return fmt.Sprintf(
+			"no HeartbeatNotification of %s received since %s, expected every %s",
+			e.ProducerRef,
+			since,
+			e.Interval,
+		)
+	}
+	return fmt.Sprintf(
+		"HeartbeatNotification of %s is overdue, the last one arrived at %s, expected every %s",
+		e.ProducerRef,
+		since,
+		e.Interval,
+	)
+}
+
+// Heartbeats monitors the HeartbeatNotifications of the producers
+type Heartbeats struct {
+	// Alarms receives a HeartbeatOverdueError whenever a heartbeat becomes overdue
+	Alarms       <-chan error
+	alarmsWriter chan error
+	mu           sync.Mutex
+	producers    map[string]*producerHeartbeat
+	// waiting are the producers which are expected to send heartbeats but did not send one yet
+	waiting map[string]*waitingHeartbeat
+}
+
+// producerHeartbeat is the monitoring state of a producer which sent heartbeats
+type producerHeartbeat struct {
+	ProducerHeartbeat
+	// paused is true while no subscription expects heartbeats of the producer
+	paused bool
+	// expectedSince is the time heartbeats were expected again after a pause
+	expectedSince time.Time
+}
+
+// waitingHeartbeat is the monitoring state of a producer which did not send a heartbeat yet
+type waitingHeartbeat struct {
+	since   time.Time
+	alarmed bool
+}
+
+// NewHeartbeats creates a monitor without any received heartbeats
+func NewHeartbeats() *Heartbeats {
+	alarms := make(chan error, 5)
+	return &Heartbeats{
+		Alarms:       alarms,
+		alarmsWriter: alarms,
+		producers:    map[string]*producerHeartbeat{},
+		waiting:      map[string]*waitingHeartbeat{},
+	}
+}
+
+// List returns the last heartbeat of every producer sorted by the producer
+func (h *Heartbeats) List() []ProducerHeartbeat {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	heartbeats := make([]ProducerHeartbeat, 0, len(h.producers))
+	for _, producerRef := range slices.Sorted(maps.Keys(h.producers)) {
+		heartbeats = append(heartbeats, h.producers[producerRef].ProducerHeartbeat)
+	}
+	return heartbeats
+}
+
+// receive records a HeartbeatNotification of the producer
+func (h *Heartbeats) receive(producerRef string, at time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	producer, ok := h.producers[producerRef]
+	if !ok {
+		producer = &producerHeartbeat{ProducerHeartbeat: ProducerHeartbeat{ProducerRef: producerRef}}
+		h.producers[producerRef] = producer
+	}
+	if producer.Overdue {
+		slog.Info("HeartbeatNotification received again", slog.String("producer", producerRef))
+	}
+	producer.LastHeartbeat = at
+	producer.Overdue = false
+}
+
+// check returns the alarms of heartbeats which became overdue. The intervals are the expected
+// HeartbeatIntervals by producer, the interval of an empty producer applies to all producers without
+// their own interval. A heartbeat is overdue if it did not arrive within one and a half times the interval.
+// Without an interval no heartbeats are expected.
+func (h *Heartbeats) check(now time.Time, intervals map[string]time.Duration) []error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var alarms []error
+	for _, producerRef := range slices.Sorted(maps.Keys(h.producers)) {
+		producer := h.producers[producerRef]
+		interval, ok := intervals[producerRef]
+		if !ok {
+			interval = intervals[""]
+		}
+		if interval <= 0 {
+			producer.paused = true
+			producer.Overdue = false
+			continue
+		}
+		if producer.paused {
+			// heartbeats received before the pause must not count as overdue
+			producer.paused = false
+			producer.expectedSince = now
+		}
+		last := producer.LastHeartbeat
+		if producer.expectedSince.After(last) {
+			last = producer.expectedSince
+		}
+		if producer.Overdue || now.Sub(last) <= deadline(interval) {
+			continue
+		}
+		producer.Overdue = true
+		alarms = append(alarms, &HeartbeatOverdueError{
+			ProducerRef:   producerRef,
+			LastHeartbeat: producer.LastHeartbeat,
+			Interval:      interval,
+		})
+	}
+
+	for producerRef := range h.waiting {
+		if intervals[producerRef] <= 0 || h.received(producerRef, intervals) {
+			delete(h.waiting, producerRef)
+		}
+	}
+	for _, producerRef := range slices.Sorted(maps.Keys(intervals)) {
+		interval := intervals[producerRef]
+		if interval <= 0 || h.received(producerRef, intervals) {
+			continue
+		}
+		waiting, ok := h.waiting[producerRef]
+		if !ok {
+			waiting = &waitingHeartbeat{since: now}
+			h.waiting[producerRef] = waiting
+		}
+		if waiting.alarmed || now.Sub(waiting.since) <= deadline(interval) {
+			continue
+		}
+		waiting.alarmed = true
+		alarms = append(alarms, &HeartbeatOverdueError{
+			ProducerRef:   producerRef,
+			LastHeartbeat: waiting.since,
+			Interval:      interval,
+			Missing:       true,
+		})
+	}
+	return alarms
+}
+
+// received reports whether a producer covered by the interval of the producerRef sent a heartbeat.
+// Heartbeats of every producer without its own interval count for an empty producerRef.
+func (h *Heartbeats) received(producerRef string, intervals map[string]time.Duration) bool {
+	if producerRef != "" {
+		_, ok := h.producers[producerRef]
+		return ok
+	}
+	for received := range h.producers {
+		if _, own := intervals[received]; !own || received == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// deadline is the time after the last heartbeat at which the next one is overdue
+func deadline(interval time.Duration) time.Duration {
+	return interval + interval/2
+}
+
+// MonitorHeartbeats checks every checkInterval whether a heartbeat is overdue until the context is done.
+// Every producer is expected to send heartbeats at the shortest HeartbeatInterval of its requested and
+// active subscriptions. Alarms are logged and sent to the Alarms of the Heartbeats.
+func (c *Client) MonitorHeartbeats(ctx context.Context, checkInterval time.Duration) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			intervals := c.Subscriptions.heartbeatIntervals(now)
+			for _, alarm := range c.Heartbeats.check(now, intervals) {
+				slog.Warn("Heartbeat overdue", slog.Any("error", alarm))
+				select {
+				case c.Heartbeats.alarmsWriter <- alarm:
+				default:
+				}
+			}
+		}
+	}
+}
+
+var xsdDurationRegexp = regexp.MustCompile(
+	`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`,
+)
+
+// parseXSDDuration parses durations like PT1M or P1DT2H30S as used by the HeartbeatInterval.
+// Years and months are not supported since their length is not fixed.
+func parseXSDDuration(value string) (time.Duration, error) {
+	matches := xsdDurationRegexp.FindStringSubmatch(value)
+	if matches == nil || value == "P" || value[len(value)-1] == 'T' {
+		return 0, fmt.Errorf("invalid duration %q, use for example PT1M", value)
+	}
+	var duration time.Duration
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if matches[i+1] == "" {
+			continue
+		}
+		amount, err := strconv.ParseFloat(matches[i+1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", value, err)
+		}
+		duration += time.Duration(amount * float64(unit))
+	}
+	return duration, nil
+}
@@ -0,0 +1,235 @@
+package siri
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_xsd_durations_are_parsed(t *testing.T) {
+	testCases := map[string]struct {
+		value    string
+		expected time.Duration
+		valid    bool
+	}{
+		"minute":          {"PT1M", time.Minute, true},
+		"all units":       {"P1DT2H3M4S", 26*time.Hour + 3*time.Minute + 4*time.Second, true},
+		"fraction":        {"PT0.5S", 500 * time.Millisecond, true},
+		"no unit":         {"P", 0, false},
+		"no time unit":    {"PT", 0, false},
+		"months":          {"P1M", 0, false},
+		"go duration":     {"1m", 0, false},
+		"negative":        {"-PT1M", 0, false},
+		"missing time T":  {"P1H", 0, false},
+		"only days valid": {"P2D", 48 * time.Hour, true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// When
+			actual, err := parseXSDDuration(tc.value)
+
+			// Then
+			if !tc.valid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func Test_subscriptions_use_heartbeat_interval_of_subscription_context(t *testing.T) {
+	// Given
+	subscriptions := NewSubscriptions()
+	subscriptionContext := "<SubscriptionContext><HeartbeatInterval>PT1M</HeartbeatInterval></SubscriptionContext>"
+	request := strings.Replace(
+		etSubscriptionRequest,
+		"<RequestorRef>client</RequestorRef>",
+		"<RequestorRef>client</RequestorRef>"+subscriptionContext,
+		1,
+	)
+
+	withoutContext := NewSubscriptions()
+
+	// When
+	subscriptions.track("URL", mustParse(t, request), nil)
+	withoutContext.track("URL", mustParse(t, etSubscriptionRequest), nil)
+
+	// Then
+	assert.Equal(t, time.Minute, subscriptions.List()[0].HeartbeatInterval)
+	assert.Equal(t, map[string]time.Duration{"": time.Minute}, subscriptions.heartbeatIntervals(time.Now()))
+	assert.Empty(t, withoutContext.heartbeatIntervals(time.Now()))
+}
+
+func Test_heartbeats_are_overdue_after_one_and_a_half_intervals(t *testing.T) {
+	// Given
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	heartbeats := NewHeartbeats()
+	heartbeats.receive("producer", start)
+	intervals := map[string]time.Duration{"": time.Minute}
+
+	// When
+	inTime := heartbeats.check(start.Add(90*time.Second), intervals)
+	overdue := heartbeats.check(start.Add(91*time.Second), intervals)
+	again := heartbeats.check(start.Add(10*time.Minute), intervals)
+
+	// Then
+	assert.Empty(t, inTime)
+	require.Len(t, overdue, 1)
+	assert.Equal(
+		t,
+		&HeartbeatOverdueError{ProducerRef: "producer", LastHeartbeat: start, Interval: time.Minute},
+		overdue[0],
+	)
+	assert.Empty(t, again, "an overdue heartbeat is only reported once")
+	assert.True(t, heartbeats.List()[0].Overdue)
+
+	// When
+	heartbeats.receive("producer", start.Add(11*time.Minute))
+
+	// Then
+	assert.False(t, heartbeats.List()[0].Overdue)
+}
+
+func Test_missing_first_heartbeat_is_overdue(t *testing.T) {
+	// Given
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	heartbeats := NewHeartbeats()
+	intervals := map[string]time.Duration{"": time.Minute}
+
+	// When
+	notExpected := heartbeats.check(start, nil)
+	firstCheck := heartbeats.check(start, intervals)
+	overdue := heartbeats.check(start.Add(2*time.Minute), intervals)
+
+	// Then
+	assert.Empty(t, notExpected)
+	assert.Empty(t, firstCheck)
+	require.Len(t, overdue, 1)
+	assert.EqualError(t, overdue[0], "no HeartbeatNotification received since "+
+		start.Local().Format(time.TimeOnly)+", expected every 1m0s")
+}
+
+func Test_subscriptions_use_heartbeat_interval_by_producer(t *testing.T) {
+	// Given
+	now := time.Now()
+	subscriptions := NewSubscriptions()
+	subscriptions.put(Subscription{Identifier: "1", Status: SubscriptionActive, HeartbeatInterval: time.Minute})
+	subscriptions.put(Subscription{
+		Identifier:        "2",
+		Status:            SubscriptionActive,
+		HeartbeatInterval: time.Hour,
+		ProducerRef:       "slow",
+	})
+	subscriptions.put(Subscription{
+		Identifier:        "3",
+		Status:            SubscriptionActive,
+		HeartbeatInterval: 10 * time.Minute,
+		ProducerRef:       "slow",
+	})
+	subscriptions.put(Subscription{
+		Identifier:        "4",
+		Status:            SubscriptionTerminated,
+		HeartbeatInterval: time.Second,
+		ProducerRef:       "slow",
+	})
+
+	// When
+	actual := subscriptions.heartbeatIntervals(now)
+
+	// Then
+	assert.Equal(t, map[string]time.Duration{"": time.Minute, "slow": 10 * time.Minute}, actual)
+}
+
+func Test_heartbeats_are_checked_against_the_interval_of_their_producer(t *testing.T) {
+	// Given
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	heartbeats := NewHeartbeats()
+	heartbeats.receive("fast", start)
+	heartbeats.receive("slow", start)
+	intervals := map[string]time.Duration{"fast": time.Minute, "slow": time.Hour}
+
+	// When
+	alarms := heartbeats.check(start.Add(10*time.Minute), intervals)
+
+	// Then
+	require.Len(t, alarms, 1)
+	assert.Equal(
+		t,
+		&HeartbeatOverdueError{ProducerRef: "fast", LastHeartbeat: start, Interval: time.Minute},
+		alarms[0],
+	)
+}
+
+func Test_missing_first_heartbeat_of_known_producer_is_overdue(t *testing.T) {
+	// Given
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	heartbeats := NewHeartbeats()
+	heartbeats.receive("other", start)
+	intervals := map[string]time.Duration{"producer": time.Minute}
+
+	// When
+	firstCheck := heartbeats.check(start, intervals)
+	overdue := heartbeats.check(start.Add(2*time.Minute), intervals)
+
+	// Then
+	assert.Empty(t, firstCheck)
+	require.Len(t, overdue, 1)
+	assert.EqualError(t, overdue[0], "no HeartbeatNotification of producer received since "+
+		start.Local().Format(time.TimeOnly)+", expected every 1m0s")
+}
+
+func Test_heartbeats_are_expected_again_from_the_time_an_interval_is_set_again(t *testing.T) {
+	// Given
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	heartbeats := NewHeartbeats()
+	heartbeats.receive("producer", start)
+	intervals := map[string]time.Duration{"": time.Minute}
+
+	// When
+	paused := heartbeats.check(start.Add(time.Hour), nil)
+	resumed := heartbeats.check(start.Add(2*time.Hour), intervals)
+	overdue := heartbeats.check(start.Add(2*time.Hour+91*time.Second), intervals)
+
+	// Then
+	assert.Empty(t, paused)
+	assert.Empty(t, resumed, "the heartbeat before the pause is not overdue right away")
+	require.Len(t, overdue, 1)
+	assert.Equal(
+		t,
+		&HeartbeatOverdueError{ProducerRef: "producer", LastHeartbeat: start, Interval: time.Minute},
+		overdue[0],
+	)
+}
+
+func Test_siri_client_monitors_heartbeats_of_server(t *testing.T) {
+	// Given
+	client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+	client.Subscriptions.put(Subscription{
+		Identifier:        "1",
+		Status:            SubscriptionActive,
+		HeartbeatInterval: 20 * time.Millisecond,
+	})
+	heartbeat := "<HeartbeatNotification><ProducerRef>producer</ProducerRef><Status>true</Status></HeartbeatNotification>"
+	request, _ := http.NewRequest(http.MethodPost, "/siri", strings.NewReader("<Siri>"+heartbeat+"</Siri>"))
+	client.createHandler().ServeHTTP(httptest.NewRecorder(), request)
+
+	// When
+	go client.MonitorHeartbeats(t.Context(), 5*time.Millisecond)
+
+	// Then
+	select {
+	case alarm := <-client.Heartbeats.Alarms:
+		assert.ErrorContains(t, alarm, "HeartbeatNotification of producer is overdue")
+	case <-time.After(time.Second):
+		assert.Fail(t, "no alarm raised")
+	}
+	assert.Equal(t, "producer", client.Heartbeats.List()[0].ProducerRef)
+}
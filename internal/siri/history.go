@@ -0,0 +1,174 @@
+package siri
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mszalbach/sirigo/internal/httputils"
+)
+
+const (
+	// historyLimit is the maximum number of exchanges kept in the history, older ones are dropped
+	historyLimit = 1000
+	// historySizeLimit is the maximum size of the bodies kept in the history in bytes, older exchanges are dropped.
+	// The latest exchange is always kept.
+	historySizeLimit = 64 << 20
+)
+
+// Direction tells who started an exchange
+type Direction string
+
+const (
+	// Outgoing is used for requests sent by the client to the SIRI server
+	Outgoing Direction = "outgoing"
+	// Incoming is used for requests sent by the SIRI server to the client
+	Incoming Direction = "incoming"
+)
+
+// Exchange is a request and its response as it was sent over the wire
+type Exchange struct {
+	Time      time.Time `json:"time"`
+	Direction Direction `json:"direction"`
+	Method    string    `json:"method"`
+	// RemoteAddress is the address of the SIRI server for incoming requests
+	RemoteAddress    string      `json:"remoteAddress,omitempty"`
+	URL              string      `json:"url"`
+	RequestHeader    http.Header `json:"requestHeader,omitempty"`
+	RequestBody      string      `json:"requestBody"`
+	RequestLanguage  string      `json:"-"`
+	Status           int         `json:"status,omitempty"`
+	ResponseHeader   http.Header `json:"responseHeader,omitempty"`
+	ResponseBody     string      `json:"responseBody,omitempty"`
+	ResponseLanguage string      `json:"-"`
+	// Duration is the time between receiving the request and sending the response in nanoseconds
+	Duration time.Duration `json:"duration"`
+	// Error is set when no response was received
+	Error string `json:"error,omitempty"`
+	// Push is set for requests sent to a push target instead of the SIRI server
+	Push bool `json:"push,omitempty"`
+}
+
+// size returns the size of the request and response body in bytes
+func (e Exchange) size() int {
+	return len(e.RequestBody) + len(e.ResponseBody)
+}
+
+// Name returns the name of the message in the request body like SubscriptionRequest
+func (e Exchange) Name() string {
+	return MessageElementName(e.RequestBody)
+}
+
+// ClientRequest returns the request of an outgoing exchange so it can be sent again.
+// Redacted credentials are left out, they are added again by the authentication of the client.
+func (e Exchange) ClientRequest() ClientRequest {
+	header := http.Header{}
+	for name, values := range e.RequestHeader {
+		// the body is recorded decompressed and compressed again when it is sent
+		if name == httputils.HeaderContentEncoding {
+			continue
+		}
+		if !slices.Contains(values, httputils.Redacted) {
+			header[name] = values
+		}
+	}
+	return ClientRequest{URL: e.URL, Body: e.RequestBody, Header: header, Push: e.Push}
+}
+
+// History keeps the latest exchanges of a client in the order they happened
+type History struct {
+	// Changed receives a value whenever an exchange was added
+	Changed       <-chan struct{}
+	changedWriter chan struct{}
+	mu            sync.Mutex
+	exchanges     []Exchange
+	// size is the size of the bodies of all exchanges in bytes
+	size int
+	log  *json.Encoder
+}
+
+// NewHistory creates an empty history
+func NewHistory() *History {
+	changed := make(chan struct{}, 1)
+	return &History{
+		Changed:       changed,
+		changedWriter: changed,
+	}
+}
+
+// List returns a copy of all exchanges, the oldest first
+func (h *History) List() []Exchange {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.exchanges)
+}
+
+// LogTo writes every exchange added from now on as JSON line into the writer
+func (h *History) LogTo(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.log = json.NewEncoder(w)
+	// keep the XML bodies readable
+	h.log.SetEscapeHTML(false)
+}
+
+// Add appends an exchange and drops the oldest one if the history is full
+func (h *History) Add(exchange Exchange) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.log != nil {
+		if err := h.log.Encode(exchange); err != nil {
+			slog.Warn("Could not write exchange log", slog.Any("error", err))
+		}
+	}
+	h.append(exchange)
+}
+
+// Import appends exchanges of a previous session without writing them into the exchange log
+func (h *History) Import(exchanges []Exchange) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, exchange := range exchanges {
+		h.append(exchange)
+	}
+}
+
+func (h *History) append(exchange Exchange) {
+	h.exchanges = append(h.exchanges, exchange)
+	h.size += exchange.size()
+	dropped := 0
+	for len(h.exchanges)-dropped > 1 && (len(h.exchanges)-dropped > historyLimit || h.size > historySizeLimit) {
+		h.size -= h.exchanges[dropped].size()
+		dropped++
+	}
+	h.exchanges = slices.Delete(h.exchanges, 0, dropped)
+	select {
+	case h.changedWriter <- struct{}{}:
+	default:
+	}
+}
+
+// ReadExchanges reads an exchange log written by History.LogTo
+func ReadExchanges(r io.Reader) ([]Exchange, error) {
+	var exchanges []Exchange
+	decoder := json.NewDecoder(r)
+	for {
+		var exchange Exchange
+		err := decoder.Decode(&exchange)
+		if errors.Is(err, io.EOF) {
+			return exchanges, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid exchange %d: %w", len(exchanges)+1, err)
+		}
+		exchange.RequestLanguage = httputils.GetLanguage(exchange.RequestHeader)
+		exchange.ResponseLanguage = httputils.GetLanguage(exchange.ResponseHeader)
+		exchanges = append(exchanges, exchange)
+	}
+}
@@ -0,0 +1,153 @@
+package siri
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_history_drops_oldest_exchanges(t *testing.T) {
+	// Given
+	history := NewHistory()
+
+	// When
+	for i := range historyLimit + 2 {
+		history.Add(Exchange{URL: strconv.Itoa(i)})
+	}
+
+	// Then
+	exchanges := history.List()
+	require.Len(t, exchanges, historyLimit)
+	assert.Equal(t, "2", exchanges[0].URL)
+	assert.Equal(t, strconv.Itoa(historyLimit+1), exchanges[historyLimit-1].URL)
+	assert.Len(t, history.Changed, 1)
+}
+
+func Test_history_drops_oldest_exchanges_above_size_limit(t *testing.T) {
+	// Given
+	history := NewHistory()
+	body := strings.Repeat("x", historySizeLimit/4)
+
+	// When
+	for i := range 3 {
+		history.Add(Exchange{URL: strconv.Itoa(i), RequestBody: body, ResponseBody: body})
+	}
+
+	// Then
+	exchanges := history.List()
+	require.Len(t, exchanges, 2)
+	assert.Equal(t, "1", exchanges[0].URL)
+	assert.Equal(t, "2", exchanges[1].URL)
+}
+
+func Test_history_keeps_latest_exchange_above_size_limit(t *testing.T) {
+	// Given
+	history := NewHistory()
+
+	// When
+	history.Add(Exchange{URL: "small"})
+	history.Add(Exchange{URL: "large", RequestBody: strings.Repeat("x", historySizeLimit+1)})
+
+	// Then
+	exchanges := history.List()
+	require.Len(t, exchanges, 1)
+	assert.Equal(t, "large", exchanges[0].URL)
+}
+
+func Test_exchange_name_is_the_message_element(t *testing.T) {
+	// Given
+	exchange := Exchange{RequestBody: etSubscriptionRequest}
+
+	// When
+	name := exchange.Name()
+
+	// Then
+	assert.Equal(t, "SubscriptionRequest", name)
+}
+
+func Test_exchange_log_can_be_read_again(t *testing.T) {
+	// Given
+	var log bytes.Buffer
+	history := NewHistory()
+	history.LogTo(&log)
+	exchange := Exchange{
+		Time:           time.Date(2004, 12, 17, 9, 30, 47, 0, time.UTC),
+		Direction:      Incoming,
+		Method:         http.MethodPost,
+		RemoteAddress:  "196.4.4.1",
+		URL:            "/siri",
+		RequestHeader:  http.Header{"Content-Type": {"application/xml"}},
+		RequestBody:    "<Siri><DataReadyNotification/></Siri>",
+		Status:         http.StatusOK,
+		ResponseHeader: http.Header{"Content-Type": {"text/xml"}},
+		ResponseBody:   "<Siri><DataReadyAcknowledgement/></Siri>",
+		Duration:       3 * time.Millisecond,
+	}
+
+	// When
+	history.Add(exchange)
+	history.Add(Exchange{Direction: Outgoing, URL: "http://localhost:1", Error: "connection refused"})
+	lines := strings.Count(log.String(), "\n")
+	exchanges, err := ReadExchanges(&log)
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, exchanges, 2)
+	exchange.RequestLanguage = "xml"
+	exchange.ResponseLanguage = "xml"
+	assert.Equal(t, exchange, exchanges[0])
+	assert.Equal(t, "connection refused", exchanges[1].Error)
+	assert.Equal(t, 2, lines)
+}
+
+func Test_imported_exchanges_are_not_logged(t *testing.T) {
+	// Given
+	var log bytes.Buffer
+	history := NewHistory()
+	history.LogTo(&log)
+
+	// When
+	history.Import([]Exchange{{URL: "/first"}, {URL: "/second"}})
+
+	// Then
+	assert.Len(t, history.List(), 2)
+	assert.Empty(t, log.String())
+}
+
+func Test_invalid_exchange_log_is_reported(t *testing.T) {
+	// When
+	_, err := ReadExchanges(strings.NewReader("{\"url\": \"/siri\"}\nno json"))
+
+	// Then
+	assert.ErrorContains(t, err, "invalid exchange 2")
+}
+
+func Test_exchange_client_request_leaves_out_redacted_credentials_and_encoding(t *testing.T) {
+	// Given
+	exchange := Exchange{
+		URL:         "http://server/siri",
+		RequestBody: "<Siri/>",
+		RequestHeader: http.Header{
+			"Content-Type":     {"application/xml"},
+			"Authorization":    {"[redacted]"},
+			"Content-Encoding": {"gzip"},
+			"X-Request-Id":     {"42"},
+		},
+	}
+
+	// When
+	actual := exchange.ClientRequest()
+
+	// Then
+	assert.Equal(t, ClientRequest{
+		URL:    "http://server/siri",
+		Body:   "<Siri/>",
+		Header: http.Header{"Content-Type": {"application/xml"}, "X-Request-Id": {"42"}},
+	}, actual)
+}
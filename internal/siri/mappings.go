@@ -0,0 +1,216 @@
+package siri
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Folders of a wiremock root folder
+const (
+	mappingsFolder = "mappings"
+	filesFolder    = "__files"
+)
+
+// Mapping is a mock response rule in the format of the wiremock mapping files.
+// Only a subset is supported: the exact url, the method, matchesXPath body patterns, the status, headers,
+// bodyFileName and fixedDelayMilliseconds.
+type Mapping struct {
+	Name     string          `json:"name,omitempty"`
+	Request  MappingRequest  `json:"request"`
+	Response MappingResponse `json:"response"`
+	// File is the name of the mapping file
+	File string `json:"-"`
+}
+
+// MappingRequest describes which requests a Mapping answers, all set conditions must match
+type MappingRequest struct {
+	// URL is compared with the path and query of the request
+	URL string `json:"url,omitempty"`
+	// Method is the HTTP method, ANY or an empty method matches all methods
+	Method       string        `json:"method,omitempty"`
+	BodyPatterns []BodyPattern `json:"bodyPatterns,omitempty"`
+}
+
+// BodyPattern is a condition for the request body
+type BodyPattern struct {
+	// MatchesXPath must find an element in the body, see MatchesSelector for the supported expressions
+	MatchesXPath string `json:"matchesXPath"`
+}
+
+// MappingResponse is the response of a Mapping
+type MappingResponse struct {
+	Status                 int               `json:"status,omitempty"`
+	Headers                map[string]string `json:"headers,omitempty"`
+	BodyFileName           string            `json:"bodyFileName,omitempty"`
+	FixedDelayMilliseconds int               `json:"fixedDelayMilliseconds,omitempty"`
+	// Body is the content of the BodyFileName
+	Body string `json:"-"`
+}
+
+// LoadMappings reads all mapping files of the mappings folder within a wiremock root folder
+// and the body files they reference from the __files folder. The mappings are sorted by file name.
+func LoadMappings(dir string) ([]Mapping, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	files, err := fs.Glob(root.FS(), mappingsFolder+"/*.json")
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no mapping files found in %s", path.Join(dir, mappingsFolder))
+	}
+	slices.Sort(files)
+
+	mappings := make([]Mapping, 0, len(files))
+	var errs []error
+	for _, file := range files {
+		mapping, err := loadMapping(root, file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mapping %s: %w", file, err))
+			continue
+		}
+		mappings = append(mappings, mapping)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
+
+func loadMapping(root *os.Root, file string) (Mapping, error) {
+	content, err := root.ReadFile(file)
+	if err != nil {
+		return Mapping{}, err
+	}
+	var mapping Mapping
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	// unsupported wiremock features are rejected instead of matching more requests than intended
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&mapping); err != nil {
+		return Mapping{}, err
+	}
+	mapping.File = file
+	if mapping.Response.Status == 0 {
+		mapping.Response.Status = http.StatusOK
+	}
+	for _, pattern := range mapping.Request.BodyPatterns {
+		if _, err := parseSelector(pattern.MatchesXPath); err != nil {
+			return Mapping{}, err
+		}
+	}
+	if mapping.Response.BodyFileName != "" {
+		body, err := root.ReadFile(path.Join(filesFolder, mapping.Response.BodyFileName))
+		if err != nil {
+			return Mapping{}, err
+		}
+		mapping.Response.Body = string(body)
+	}
+	return mapping, nil
+}
+
+// Matches reports whether the method, path with query and body of a request match the mapping
+func (m Mapping) Matches(method string, requestURI string, body string) bool {
+	if m.Request.Method != "" && m.Request.Method != "ANY" && !strings.EqualFold(m.Request.Method, method) {
+		return false
+	}
+	if m.Request.URL != "" && m.Request.URL != requestURI {
+		return false
+	}
+	for _, pattern := range m.Request.BodyPatterns {
+		if matched, err := MatchesSelector(body, pattern.MatchesXPath); err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// Delay is the time to wait before the response is sent
+func (m Mapping) Delay() time.Duration {
+	return time.Duration(m.Response.FixedDelayMilliseconds) * time.Millisecond
+}
+
+// findMapping returns the first mapping matching the request
+func findMapping(mappings []Mapping, method string, requestURI string, body string) (Mapping, bool) {
+	for _, mapping := range mappings {
+		if mapping.Matches(method, requestURI, body) {
+			return mapping, true
+		}
+	}
+	return Mapping{}, false
+}
+
+var (
+	handlebarsRegexp = regexp.MustCompile(`\{\{\s*(now|xPath)\b(.*?)\}\}`)
+	offsetRegexp     = regexp.MustCompile(`offset=['"]([^'"]*)['"]`)
+	xPathRegexp      = regexp.MustCompile(`^\s*request\.body\s+['"](.*)['"]\s*$`)
+)
+
+// renderMappingBody replaces the wiremock response template helpers {{now}}, {{now offset='3 hours'}}
+// and {{xPath request.body '/Siri/...'}}. Other helpers are left unchanged.
+func renderMappingBody(body string, requestBody string, now time.Time) string {
+	return handlebarsRegexp.ReplaceAllStringFunc(body, func(helper string) string {
+		matches := handlebarsRegexp.FindStringSubmatch(helper)
+		switch matches[1] {
+		case "now":
+			var duration time.Duration
+			if offset := offsetRegexp.FindStringSubmatch(matches[2]); offset != nil {
+				var err error
+				if duration, err = parseOffset(offset[1]); err != nil {
+					slog.Warn("Invalid offset in response template", slog.String("helper", helper), slog.Any("error", err))
+				}
+			}
+			return now.Add(duration).UTC().Format(time.RFC3339)
+		default:
+			selector := xPathRegexp.FindStringSubmatch(matches[2])
+			if selector == nil {
+				return helper
+			}
+			text, err := SelectText(requestBody, selector[1])
+			if err != nil {
+				slog.Warn("Invalid xPath in response template", slog.String("helper", helper), slog.Any("error", err))
+			}
+			return text
+		}
+	})
+}
+
+// offsetUnits are the units of now offsets like '3 hours' or '-10 minutes'
+var offsetUnits = map[string]time.Duration{
+	"seconds": time.Second,
+	"minutes": time.Minute,
+	"hours":   time.Hour,
+	"days":    24 * time.Hour,
+	"weeks":   7 * 24 * time.Hour,
+}
+
+func parseOffset(offset string) (time.Duration, error) {
+	amount, unit, _ := strings.Cut(strings.TrimSpace(offset), " ")
+	value, err := strconv.Atoi(amount)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset %q, use for example '3 hours'", offset)
+	}
+	unit = strings.ToLower(strings.TrimSpace(unit))
+	duration, ok := offsetUnits[unit]
+	if !ok {
+		duration, ok = offsetUnits[unit+"s"]
+	}
+	if !ok {
+		return 0, fmt.Errorf("invalid offset unit in %q, use seconds, minutes, hours, days or weeks", offset)
+	}
+	return time.Duration(value) * duration, nil
+}
@@ -0,0 +1,143 @@
+package siri
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeMapping creates a wiremock root folder with one mapping and the body file hello.xml
+func writeMapping(t *testing.T, mapping string) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "mappings"), 0o750))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "__files"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mappings", "hello.json"), []byte(mapping), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "__files", "hello.xml"), []byte("<Hello/>"), 0o600))
+	return dir
+}
+
+func Test_wiremock_mappings_of_the_repository_are_loaded(t *testing.T) {
+	// When
+	mappings, err := LoadMappings("../../wiremock")
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, mappings, 4)
+	assert.Equal(t, "mappings/et_Subscription.json", mappings[0].File)
+	slow := mappings[2]
+	assert.Equal(t, "mappings/et_dataSupply_slow.json", slow.File)
+	assert.Equal(t, 5*time.Second, slow.Delay())
+	assert.Equal(t, http.StatusOK, slow.Response.Status)
+	assert.Equal(t, "text/xml", slow.Response.Headers["Content-Type"])
+	assert.Contains(t, slow.Response.Body, "<EstimatedTimetableDelivery>")
+}
+
+func Test_invalid_mappings_are_an_error(t *testing.T) {
+	testCases := map[string]struct {
+		mapping       string
+		expectedError string
+	}{
+		"unsupported field": {`{"request": {"urlPattern": "/.*"}, "response": {}}`, `unknown field "urlPattern"`},
+		"invalid xpath": {
+			`{"request": {"bodyPatterns": [{"matchesXPath": "Siri"}]}, "response": {}}`,
+			"must start with / or //",
+		},
+		"missing body file": {`{"request": {}, "response": {"bodyFileName": "missing.xml"}}`, "missing.xml"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// When
+			_, err := LoadMappings(writeMapping(t, tc.mapping))
+
+			// Then
+			assert.ErrorContains(t, err, "mappings/hello.json")
+			assert.ErrorContains(t, err, tc.expectedError)
+		})
+	}
+}
+
+func Test_mapping_matches_method_url_and_body(t *testing.T) {
+	mapping := Mapping{Request: MappingRequest{
+		Method:       http.MethodPost,
+		URL:          "/et.xml",
+		BodyPatterns: []BodyPattern{{MatchesXPath: "//DataSupplyRequest//ConsumerRef[text()!='slow']"}},
+	}}
+	dataSupply := "<Siri><DataSupplyRequest><ConsumerRef>a</ConsumerRef></DataSupplyRequest></Siri>"
+	slowDataSupply := "<Siri><DataSupplyRequest><ConsumerRef>slow</ConsumerRef></DataSupplyRequest></Siri>"
+	testCases := map[string]struct {
+		method   string
+		url      string
+		body     string
+		expected bool
+	}{
+		"match":       {http.MethodPost, "/et.xml", dataSupply, true},
+		"other body":  {http.MethodPost, "/et.xml", slowDataSupply, false},
+		"other url":   {http.MethodPost, "/vm.xml", dataSupply, false},
+		"with query":  {http.MethodPost, "/et.xml?a=1", dataSupply, false},
+		"other verb":  {http.MethodGet, "/et.xml", dataSupply, false},
+		"no xml body": {http.MethodPost, "/et.xml", "", false},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// When
+			actual := mapping.Matches(tc.method, tc.url, tc.body)
+
+			// Then
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func Test_mapping_body_helpers_are_rendered(t *testing.T) {
+	// Given
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	body := `<A>{{now}}</A><B>{{now offset='3 hours'}}</B>` +
+		`<C>{{xPath request.body '/Siri/DataSupplyRequest/ConsumerRef/text()'}}</C><D>{{randomValue length=3}}</D>`
+	request := "<Siri><DataSupplyRequest><ConsumerRef>client</ConsumerRef></DataSupplyRequest></Siri>"
+
+	// When
+	actual := renderMappingBody(body, request, now)
+
+	// Then
+	assert.Equal(
+		t,
+		`<A>2024-01-01T10:00:00Z</A><B>2024-01-01T13:00:00Z</B><C>client</C><D>{{randomValue length=3}}</D>`,
+		actual,
+	)
+}
+
+func Test_server_answers_with_mappings_and_remembers_subscriptions(t *testing.T) {
+	// Given
+	mappings, err := LoadMappings(writeMapping(t, `{
+	"request": {"method": "POST", "url": "/et", "bodyPatterns": [{"matchesXPath": "//SubscriptionRequest"}]},
+	"response": {"status": 201, "headers": {"X-Mock": "yes"}, "bodyFileName": "hello.xml", "fixedDelayMilliseconds": 10}
+}`))
+	require.NoError(t, err)
+	server, handler := newTestServer(t)
+	server.SetMappings(mappings)
+	request, _ := http.NewRequest(
+		http.MethodPost,
+		"/et",
+		strings.NewReader(subscriptionRequest("http://consumer/siri", time.Now().Add(time.Hour))),
+	)
+	response := httptest.NewRecorder()
+
+	// When
+	start := time.Now()
+	handler.ServeHTTP(response, request)
+
+	// Then
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+	assert.Equal(t, http.StatusCreated, response.Code)
+	assert.Equal(t, "yes", response.Header().Get("X-Mock"))
+	assert.Equal(t, "<Hello/>", response.Body.String())
+	assert.Len(t, server.Subscriptions(), 1)
+}
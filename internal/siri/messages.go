@@ -0,0 +1,359 @@
+package siri
+
+import (
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Namespace is the XML namespace used by SIRI messages
+const Namespace = "http://www.siri.org.uk/siri"
+
+// Message is the <Siri> envelope of a SIRI 2.x message.
+// Only one of the message fields is expected to be set.
+type Message struct {
+	XMLName xml.Name `xml:"Siri"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	Version string   `xml:"version,attr,omitempty"`
+
+	SubscriptionRequest           *SubscriptionRequest           `xml:"SubscriptionRequest,omitempty"`
+	SubscriptionResponse          *SubscriptionResponse          `xml:"SubscriptionResponse,omitempty"`
+	DataReadyNotification         *DataReadyNotification         `xml:"DataReadyNotification,omitempty"`
+	DataReadyAcknowledgement      *DataReadyAcknowledgement      `xml:"DataReadyAcknowledgement,omitempty"`
+	DataSupplyRequest             *DataSupplyRequest             `xml:"DataSupplyRequest,omitempty"`
+	ServiceDelivery               *ServiceDelivery               `xml:"ServiceDelivery,omitempty"`
+	DataReceivedAcknowledgement   *DataReceivedAcknowledgement   `xml:"DataReceivedAcknowledgement,omitempty"`
+	TerminateSubscriptionRequest  *TerminateSubscriptionRequest  `xml:"TerminateSubscriptionRequest,omitempty"`
+	TerminateSubscriptionResponse *TerminateSubscriptionResponse `xml:"TerminateSubscriptionResponse,omitempty"`
+	CheckStatusRequest            *CheckStatusRequest            `xml:"CheckStatusRequest,omitempty"`
+	CheckStatusResponse           *CheckStatusResponse           `xml:"CheckStatusResponse,omitempty"`
+	HeartbeatNotification         *HeartbeatNotification         `xml:"HeartbeatNotification,omitempty"`
+}
+
+// DateTime is a xs:dateTime of a SIRI message. Unlike time.Time it accepts values without offset,
+// they are interpreted as local time.
+type DateTime struct {
+	time.Time
+}
+
+// zonelessDateTime is the layout of a xs:dateTime without offset, fractional seconds are accepted by time.Parse
+const zonelessDateTime = "2006-01-02T15:04:05"
+
+// UnmarshalText parses a xs:dateTime with or without offset and fractional seconds
+func (d *DateTime) UnmarshalText(text []byte) error {
+	value := strings.TrimSpace(string(text))
+	if value == "" {
+		d.Time = time.Time{}
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		parsed, err = time.ParseInLocation(zonelessDateTime, value, time.Local)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid xs:dateTime %q", value)
+	}
+	d.Time = parsed
+	return nil
+}
+
+// SubscriptionRequest asks the server to set up one or more subscriptions
+type SubscriptionRequest struct {
+	RequestTimestamp DateTime `xml:"RequestTimestamp"`
+	// Address is the endpoint of the requestor, it is used for notifications if no ConsumerAddress is set
+	Address             string               `xml:"Address,omitempty"`
+	RequestorRef        string               `xml:"RequestorRef"`
+	MessageIdentifier   string               `xml:"MessageIdentifier,omitempty"`
+	ConsumerAddress     string               `xml:"ConsumerAddress,omitempty"`
+	SubscriptionContext *SubscriptionContext `xml:"SubscriptionContext,omitempty"`
+	// Subscriptions holds the service specific requests like EstimatedTimetableSubscriptionRequest
+	Subscriptions []ServiceSubscriptionRequest `xml:",any"`
+}
+
+// SubscriptionContext contains general settings for all subscriptions of a SubscriptionRequest
+type SubscriptionContext struct {
+	HeartbeatInterval string `xml:"HeartbeatInterval,omitempty"`
+}
+
+// ServiceSubscriptionRequest is a subscription for one service like EstimatedTimetable or VehicleMonitoring.
+// The service is defined by the element name.
+type ServiceSubscriptionRequest struct {
+	XMLName                xml.Name
+	SubscriberRef          string   `xml:"SubscriberRef,omitempty"`
+	SubscriptionIdentifier string   `xml:"SubscriptionIdentifier"`
+	InitialTerminationTime DateTime `xml:"InitialTerminationTime"`
+}
+
+// SubscriptionResponse is the answer of the server to a SubscriptionRequest
+type SubscriptionResponse struct {
+	ResponseTimestamp  DateTime         `xml:"ResponseTimestamp"`
+	ResponderRef       string           `xml:"ResponderRef,omitempty"`
+	RequestMessageRef  string           `xml:"RequestMessageRef,omitempty"`
+	ResponseStatus     []ResponseStatus `xml:"ResponseStatus"`
+	ServiceStartedTime *DateTime        `xml:"ServiceStartedTime,omitempty"`
+}
+
+// ResponseStatus is the status of a single subscription within a SubscriptionResponse
+type ResponseStatus struct {
+	ResponseTimestamp     DateTime        `xml:"ResponseTimestamp"`
+	RequestMessageRef     string          `xml:"RequestMessageRef,omitempty"`
+	SubscriberRef         string          `xml:"SubscriberRef,omitempty"`
+	SubscriptionRef       string          `xml:"SubscriptionRef,omitempty"`
+	Status                bool            `xml:"Status"`
+	ErrorCondition        *ErrorCondition `xml:"ErrorCondition,omitempty"`
+	ValidUntil            *DateTime       `xml:"ValidUntil,omitempty"`
+	ShortestPossibleCycle string          `xml:"ShortestPossibleCycle,omitempty"`
+}
+
+// ErrorCondition describes why a request failed
+type ErrorCondition struct {
+	// Errors holds the concrete errors like CapabilityNotSupportedError
+	Errors      []ErrorDetail `xml:",any"`
+	Description string        `xml:"Description,omitempty"`
+}
+
+// ErrorDetail is a single SIRI error. The kind of error is defined by the element name.
+type ErrorDetail struct {
+	XMLName   xml.Name
+	ErrorText string `xml:"ErrorText,omitempty"`
+}
+
+// String returns the error texts of all errors or the description if there are none
+func (ec *ErrorCondition) String() string {
+	if ec == nil {
+		return ""
+	}
+	var texts []string
+	for _, e := range ec.Errors {
+		texts = append(texts, cmp.Or(e.ErrorText, e.XMLName.Local))
+	}
+	if len(texts) == 0 {
+		return ec.Description
+	}
+	return strings.Join(texts, ", ")
+}
+
+// DataReadyNotification informs the client that new data for its subscriptions can be fetched
+type DataReadyNotification struct {
+	RequestTimestamp DateTime `xml:"RequestTimestamp"`
+	ProducerRef      string   `xml:"ProducerRef,omitempty"`
+}
+
+// DataReadyAcknowledgement is the answer of the client to a DataReadyNotification
+type DataReadyAcknowledgement struct {
+	ResponseTimestamp DateTime        `xml:"ResponseTimestamp"`
+	ConsumerRef       string          `xml:"ConsumerRef,omitempty"`
+	Status            bool            `xml:"Status"`
+	ErrorCondition    *ErrorCondition `xml:"ErrorCondition,omitempty"`
+}
+
+// DataSupplyRequest fetches the data the server announced with a DataReadyNotification
+type DataSupplyRequest struct {
+	RequestTimestamp DateTime `xml:"RequestTimestamp"`
+	ConsumerRef      string   `xml:"ConsumerRef"`
+	NotificationRef  string   `xml:"NotificationRef,omitempty"`
+	AllData          bool     `xml:"AllData,omitempty"`
+}
+
+// ServiceDelivery contains the data delivered by the server
+type ServiceDelivery struct {
+	ResponseTimestamp DateTime        `xml:"ResponseTimestamp"`
+	ProducerRef       string          `xml:"ProducerRef,omitempty"`
+	RequestMessageRef string          `xml:"RequestMessageRef,omitempty"`
+	Status            *bool           `xml:"Status,omitempty"`
+	ErrorCondition    *ErrorCondition `xml:"ErrorCondition,omitempty"`
+	MoreData          bool            `xml:"MoreData,omitempty"`
+	// Deliveries holds the service specific deliveries like EstimatedTimetableDelivery
+	Deliveries []Delivery `xml:",any"`
+}
+
+// Delivery is the delivery for one service like EstimatedTimetable or VehicleMonitoring.
+// The service is defined by the element name.
+type Delivery struct {
+	XMLName           xml.Name
+	ResponseTimestamp DateTime        `xml:"ResponseTimestamp"`
+	SubscriberRef     string          `xml:"SubscriberRef,omitempty"`
+	SubscriptionRef   string          `xml:"SubscriptionRef,omitempty"`
+	Status            *bool           `xml:"Status,omitempty"`
+	ErrorCondition    *ErrorCondition `xml:"ErrorCondition,omitempty"`
+}
+
+// DataReceivedAcknowledgement is the answer of the client to a ServiceDelivery sent in direct delivery mode
+type DataReceivedAcknowledgement struct {
+	ResponseTimestamp DateTime        `xml:"ResponseTimestamp"`
+	ConsumerRef       string          `xml:"ConsumerRef,omitempty"`
+	RequestMessageRef string          `xml:"RequestMessageRef,omitempty"`
+	Status            bool            `xml:"Status"`
+	ErrorCondition    *ErrorCondition `xml:"ErrorCondition,omitempty"`
+}
+
+// TerminateSubscriptionRequest ends some or all subscriptions of the requestor
+type TerminateSubscriptionRequest struct {
+	RequestTimestamp DateTime  `xml:"RequestTimestamp"`
+	RequestorRef     string    `xml:"RequestorRef"`
+	All              *struct{} `xml:"All,omitempty"`
+	SubscriptionRefs []string  `xml:"SubscriptionRef,omitempty"`
+}
+
+// TerminateSubscriptionResponse is the answer of the server to a TerminateSubscriptionRequest
+type TerminateSubscriptionResponse struct {
+	ResponseTimestamp         DateTime                    `xml:"ResponseTimestamp"`
+	ResponderRef              string                      `xml:"ResponderRef,omitempty"`
+	TerminationResponseStatus []TerminationResponseStatus `xml:"TerminationResponseStatus"`
+}
+
+// TerminationResponseStatus is the status of a single terminated subscription
+type TerminationResponseStatus struct {
+	ResponseTimestamp DateTime        `xml:"ResponseTimestamp"`
+	SubscriberRef     string          `xml:"SubscriberRef,omitempty"`
+	SubscriptionRef   string          `xml:"SubscriptionRef,omitempty"`
+	Status            bool            `xml:"Status"`
+	ErrorCondition    *ErrorCondition `xml:"ErrorCondition,omitempty"`
+}
+
+// CheckStatusRequest asks the other side whether it is still alive
+type CheckStatusRequest struct {
+	RequestTimestamp DateTime `xml:"RequestTimestamp"`
+	RequestorRef     string   `xml:"RequestorRef,omitempty"`
+}
+
+// CheckStatusResponse is the answer to a CheckStatusRequest
+type CheckStatusResponse struct {
+	ResponseTimestamp  DateTime        `xml:"ResponseTimestamp"`
+	ProducerRef        string          `xml:"ProducerRef,omitempty"`
+	Status             bool            `xml:"Status"`
+	DataReady          *bool           `xml:"DataReady,omitempty"`
+	ErrorCondition     *ErrorCondition `xml:"ErrorCondition,omitempty"`
+	ServiceStartedTime *DateTime       `xml:"ServiceStartedTime,omitempty"`
+}
+
+// HeartbeatNotification is sent periodically by the server to show that the subscriptions are alive
+type HeartbeatNotification struct {
+	RequestTimestamp   DateTime        `xml:"RequestTimestamp"`
+	ProducerRef        string          `xml:"ProducerRef,omitempty"`
+	Status             bool            `xml:"Status"`
+	ErrorCondition     *ErrorCondition `xml:"ErrorCondition,omitempty"`
+	ServiceStartedTime *DateTime       `xml:"ServiceStartedTime,omitempty"`
+}
+
+// ParseMessage parses a SIRI XML body into a Message
+func ParseMessage(body string) (*Message, error) {
+	var message Message
+	if err := xml.Unmarshal([]byte(body), &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// siriElementRegexp finds the Siri root element with or without namespace prefix
+var siriElementRegexp = regexp.MustCompile(`<(\w+:)?Siri[\s/>]`)
+
+// TryParseMessage parses the body as SIRI message and returns nil if this is not possible.
+// Bodies which look like SIRI but cannot be parsed are logged as warning, since nothing is tracked for them.
+func TryParseMessage(body string) *Message {
+	message, err := ParseMessage(body)
+	if err != nil {
+		if siriElementRegexp.MatchString(body) {
+			slog.Warn("Could not parse SIRI message", slog.Any("error", err))
+		} else {
+			slog.Debug("Body is not a SIRI message", slog.Any("error", err))
+		}
+		return nil
+	}
+	return message
+}
+
+// Marshal converts the Message into a SIRI XML body
+func (m Message) Marshal() (string, error) {
+	if m.Xmlns == "" {
+		m.Xmlns = Namespace
+	}
+	if m.Version == "" {
+		m.Version = "2.1"
+	}
+	content, err := xml.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(content), nil
+}
+
+// Name returns the element name of the contained message like DataReadyNotification.
+// Returns an empty string if no known message is contained.
+func (m *Message) Name() string {
+	switch {
+	case m == nil:
+		return ""
+	case m.SubscriptionRequest != nil:
+		return "SubscriptionRequest"
+	case m.SubscriptionResponse != nil:
+		return "SubscriptionResponse"
+	case m.DataReadyNotification != nil:
+		return "DataReadyNotification"
+	case m.DataReadyAcknowledgement != nil:
+		return "DataReadyAcknowledgement"
+	case m.DataSupplyRequest != nil:
+		return "DataSupplyRequest"
+	case m.ServiceDelivery != nil:
+		return "ServiceDelivery"
+	case m.DataReceivedAcknowledgement != nil:
+		return "DataReceivedAcknowledgement"
+	case m.TerminateSubscriptionRequest != nil:
+		return "TerminateSubscriptionRequest"
+	case m.TerminateSubscriptionResponse != nil:
+		return "TerminateSubscriptionResponse"
+	case m.CheckStatusRequest != nil:
+		return "CheckStatusRequest"
+	case m.CheckStatusResponse != nil:
+		return "CheckStatusResponse"
+	case m.HeartbeatNotification != nil:
+		return "HeartbeatNotification"
+	}
+	return ""
+}
+
+// Status returns whether the contained message reports success.
+// All statuses of a message must be true, found is false if the message has no status at all.
+func (m *Message) Status() (status bool, found bool) {
+	if m == nil {
+		return false, false
+	}
+	var statuses []bool
+	switch {
+	case m.SubscriptionResponse != nil:
+		for _, responseStatus := range m.SubscriptionResponse.ResponseStatus {
+			statuses = append(statuses, responseStatus.Status)
+		}
+	case m.TerminateSubscriptionResponse != nil:
+		for _, terminationStatus := range m.TerminateSubscriptionResponse.TerminationResponseStatus {
+			statuses = append(statuses, terminationStatus.Status)
+		}
+	case m.DataReadyAcknowledgement != nil:
+		statuses = append(statuses, m.DataReadyAcknowledgement.Status)
+	case m.ServiceDelivery != nil:
+		if m.ServiceDelivery.Status != nil {
+			statuses = append(statuses, *m.ServiceDelivery.Status)
+		}
+		for _, delivery := range m.ServiceDelivery.Deliveries {
+			if delivery.Status != nil {
+				statuses = append(statuses, *delivery.Status)
+			}
+		}
+	case m.DataReceivedAcknowledgement != nil:
+		statuses = append(statuses, m.DataReceivedAcknowledgement.Status)
+	case m.CheckStatusResponse != nil:
+		statuses = append(statuses, m.CheckStatusResponse.Status)
+	case m.HeartbeatNotification != nil:
+		statuses = append(statuses, m.HeartbeatNotification.Status)
+	}
+
+	if len(statuses) == 0 {
+		return false, false
+	}
+	return !slices.Contains(statuses, false), true
+}
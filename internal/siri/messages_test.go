@@ -0,0 +1,296 @@
+package siri
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parses_subscription_request(t *testing.T) {
+	// Given
+	body := `<?xml version="1.0" encoding="UTF-8"?>
+<Siri xmlns="http://www.siri.org.uk/siri" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" version="2.1">
+	<SubscriptionRequest>
+		<RequestTimestamp>2004-12-17T09:30:47Z</RequestTimestamp>
+		<RequestorRef>client</RequestorRef>
+		<SubscriptionContext>
+			<HeartbeatInterval>PT5M</HeartbeatInterval>
+		</SubscriptionContext>
+		<EstimatedTimetableSubscriptionRequest>
+			<SubscriberRef>client</SubscriberRef>
+			<SubscriptionIdentifier>1</SubscriptionIdentifier>
+			<InitialTerminationTime>2004-12-17T11:30:47Z</InitialTerminationTime>
+			<EstimatedTimetableRequest>
+				<RequestTimestamp>2004-12-17T09:30:47Z</RequestTimestamp>
+			</EstimatedTimetableRequest>
+		</EstimatedTimetableSubscriptionRequest>
+	</SubscriptionRequest>
+</Siri>`
+
+	// When
+	actual, err := ParseMessage(body)
+	require.NoError(t, err)
+
+	// Then
+	assert.Equal(t, "SubscriptionRequest", actual.Name())
+	assert.Equal(t, "2.1", actual.Version)
+	request := actual.SubscriptionRequest
+	assert.Equal(t, "client", request.RequestorRef)
+	assert.Equal(t, "PT5M", request.SubscriptionContext.HeartbeatInterval)
+	require.Len(t, request.Subscriptions, 1)
+	assert.Equal(t, "EstimatedTimetableSubscriptionRequest", request.Subscriptions[0].XMLName.Local)
+	assert.Equal(t, "1", request.Subscriptions[0].SubscriptionIdentifier)
+	terminationTime := time.Date(2004, 12, 17, 11, 30, 47, 0, time.UTC)
+	assert.True(t, terminationTime.Equal(request.Subscriptions[0].InitialTerminationTime.Time))
+}
+
+func Test_parses_xsd_date_times(t *testing.T) {
+	testCases := map[string]struct {
+		value    string
+		expected time.Time
+	}{
+		"utc":                {"2024-05-01T10:00:00Z", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		"offset":             {"2024-05-01T12:00:00+02:00", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		"fraction":           {"2024-05-01T10:00:00.25Z", time.Date(2024, 5, 1, 10, 0, 0, 250_000_000, time.UTC)},
+		"without offset":     {"2024-05-01T10:00:00", time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)},
+		"fraction no offset": {" 2024-05-01T10:00:00.5 ", time.Date(2024, 5, 1, 10, 0, 0, 500_000_000, time.Local)},
+		"empty":              {"", time.Time{}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// When
+			var actual DateTime
+			err := actual.UnmarshalText([]byte(tc.value))
+
+			// Then
+			require.NoError(t, err)
+			assert.True(t, tc.expected.Equal(actual.Time), "expected %s, got %s", tc.expected, actual.Time)
+		})
+	}
+}
+
+func Test_invalid_xsd_date_time_is_an_error(t *testing.T) {
+	// When
+	var actual DateTime
+	err := actual.UnmarshalText([]byte("2024-05-01 10:00"))
+
+	// Then
+	assert.EqualError(t, err, `invalid xs:dateTime "2024-05-01 10:00"`)
+}
+
+func Test_parses_messages_with_date_times_without_offset(t *testing.T) {
+	// Given
+	body := `<Siri><SubscriptionRequest>
+		<RequestTimestamp>2024-05-01T10:00:00</RequestTimestamp>
+		<RequestorRef>client</RequestorRef>
+		<EstimatedTimetableSubscriptionRequest>
+			<SubscriptionIdentifier>1</SubscriptionIdentifier>
+			<InitialTerminationTime>2024-05-01T12:00:00.123</InitialTerminationTime>
+		</EstimatedTimetableSubscriptionRequest>
+	</SubscriptionRequest></Siri>`
+
+	// When
+	actual := TryParseMessage(body)
+
+	// Then
+	require.NotNil(t, actual)
+	terminationTime := time.Date(2024, 5, 1, 12, 0, 0, 123_000_000, time.Local)
+	assert.True(t, terminationTime.Equal(actual.SubscriptionRequest.Subscriptions[0].InitialTerminationTime.Time))
+}
+
+func Test_parses_message_names(t *testing.T) {
+	testCases := map[string]struct {
+		body         string
+		expectedName string
+	}{
+		"DataReadyNotification": {
+			"<Siri><DataReadyNotification><ProducerRef>P</ProducerRef></DataReadyNotification></Siri>",
+			"DataReadyNotification",
+		},
+		"DataSupplyRequest": {
+			"<Siri><DataSupplyRequest><ConsumerRef>C</ConsumerRef></DataSupplyRequest></Siri>",
+			"DataSupplyRequest",
+		},
+		"ServiceDelivery": {
+			"<Siri><ServiceDelivery><ProducerRef>P</ProducerRef></ServiceDelivery></Siri>",
+			"ServiceDelivery",
+		},
+		"DataReceivedAcknowledgement": {
+			"<Siri><DataReceivedAcknowledgement><Status>true</Status></DataReceivedAcknowledgement></Siri>",
+			"DataReceivedAcknowledgement",
+		},
+		"TerminateSubscriptionResponse": {
+			"<Siri><TerminateSubscriptionResponse></TerminateSubscriptionResponse></Siri>",
+			"TerminateSubscriptionResponse",
+		},
+		"CheckStatusRequest": {
+			"<Siri><CheckStatusRequest></CheckStatusRequest></Siri>",
+			"CheckStatusRequest",
+		},
+		"HeartbeatNotification": {
+			"<Siri><HeartbeatNotification><Status>true</Status></HeartbeatNotification></Siri>",
+			"HeartbeatNotification",
+		},
+		"unknown message": {"<Siri><Unknown/></Siri>", ""},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual, err := ParseMessage(tc.body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedName, actual.Name())
+		})
+	}
+}
+
+func Test_parse_fails_for_non_siri_bodies(t *testing.T) {
+	testCases := map[string]string{
+		"empty":         "",
+		"plain text":    "Hello",
+		"other xml":     "<Vdv><Test/></Vdv>",
+		"json":          `{"Siri": {}}`,
+		"truncated xml": "<Siri><ServiceDelivery>",
+	}
+	for name, body := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseMessage(body)
+			require.Error(t, err)
+		})
+	}
+}
+
+func Test_parses_service_delivery_with_deliveries(t *testing.T) {
+	// Given
+	body := `<Siri xmlns="http://www.siri.org.uk/siri" version="2.1">
+	<ServiceDelivery>
+		<ResponseTimestamp>2004-12-17T09:30:47Z</ResponseTimestamp>
+		<ProducerRef>SIRI</ProducerRef>
+		<Status>true</Status>
+		<MoreData>false</MoreData>
+		<EstimatedTimetableDelivery>
+			<ResponseTimestamp>2004-12-17T09:30:47Z</ResponseTimestamp>
+			<SubscriberRef>client</SubscriberRef>
+			<SubscriptionRef>1</SubscriptionRef>
+			<EstimatedJourneyVersionFrame/>
+		</EstimatedTimetableDelivery>
+	</ServiceDelivery>
+</Siri>`
+
+	// When
+	actual, err := ParseMessage(body)
+	require.NoError(t, err)
+
+	// Then
+	delivery := actual.ServiceDelivery
+	assert.Equal(t, "SIRI", delivery.ProducerRef)
+	require.NotNil(t, delivery.Status)
+	assert.True(t, *delivery.Status)
+	require.Len(t, delivery.Deliveries, 1)
+	assert.Equal(t, "EstimatedTimetableDelivery", delivery.Deliveries[0].XMLName.Local)
+	assert.Equal(t, "1", delivery.Deliveries[0].SubscriptionRef)
+}
+
+func Test_marshals_message_with_namespace_and_version(t *testing.T) {
+	// Given
+	message := Message{
+		DataSupplyRequest: &DataSupplyRequest{
+			RequestTimestamp: DateTime{Time: time.Date(2004, 12, 17, 9, 30, 47, 0, time.UTC)},
+			ConsumerRef:      "client",
+		},
+	}
+
+	// When
+	actual, err := message.Marshal()
+	require.NoError(t, err)
+
+	// Then
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<Siri xmlns="http://www.siri.org.uk/siri" version="2.1">
+	<DataSupplyRequest>
+		<RequestTimestamp>2004-12-17T09:30:47Z</RequestTimestamp>
+		<ConsumerRef>client</ConsumerRef>
+	</DataSupplyRequest>
+</Siri>`
+	assert.Equal(t, expected, actual)
+}
+
+func Test_marshalled_message_can_be_parsed_again(t *testing.T) {
+	// Given
+	message := Message{
+		TerminateSubscriptionRequest: &TerminateSubscriptionRequest{
+			RequestTimestamp: DateTime{Time: time.Date(2004, 12, 17, 9, 30, 47, 0, time.UTC)},
+			RequestorRef:     "client",
+			All:              &struct{}{},
+		},
+	}
+
+	// When
+	body, err := message.Marshal()
+	require.NoError(t, err)
+	actual, err := ParseMessage(body)
+	require.NoError(t, err)
+
+	// Then
+	assert.Equal(t, "TerminateSubscriptionRequest", actual.Name())
+	assert.Equal(t, "client", actual.TerminateSubscriptionRequest.RequestorRef)
+	assert.NotNil(t, actual.TerminateSubscriptionRequest.All)
+}
+
+func Test_returns_status_of_messages(t *testing.T) {
+	testCases := map[string]struct {
+		body           string
+		expectedStatus bool
+		expectedFound  bool
+	}{
+		"successful subscription": {
+			"<Siri><SubscriptionResponse><ResponseStatus><Status>true</Status></ResponseStatus></SubscriptionResponse></Siri>",
+			true,
+			true,
+		},
+		"one failed subscription": {
+			`<Siri><SubscriptionResponse>
+				<ResponseStatus><Status>true</Status></ResponseStatus>
+				<ResponseStatus><Status>false</Status></ResponseStatus>
+			</SubscriptionResponse></Siri>`,
+			false,
+			true,
+		},
+		"failed delivery": {
+			`<Siri><ServiceDelivery><Status>true</Status>
+				<EstimatedTimetableDelivery><Status>false</Status></EstimatedTimetableDelivery>
+			</ServiceDelivery></Siri>`,
+			false,
+			true,
+		},
+		"delivery without status": {"<Siri><ServiceDelivery></ServiceDelivery></Siri>", false, false},
+		"terminated subscription": {
+			`<Siri><TerminateSubscriptionResponse>
+				<TerminationResponseStatus><Status>true</Status></TerminationResponseStatus>
+			</TerminateSubscriptionResponse></Siri>`,
+			true,
+			true,
+		},
+		"check status": {"<Siri><CheckStatusResponse><Status>true</Status></CheckStatusResponse></Siri>", true, true},
+		"request":      {"<Siri><DataSupplyRequest/></Siri>", false, false},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			message, err := ParseMessage(tc.body)
+			require.NoError(t, err)
+
+			actualStatus, actualFound := message.Status()
+
+			assert.Equal(t, tc.expectedStatus, actualStatus)
+			assert.Equal(t, tc.expectedFound, actualFound)
+		})
+	}
+}
+
+func Test_nil_message_has_no_status(t *testing.T) {
+	var message *Message
+
+	_, found := message.Status()
+
+	assert.False(t, found)
+}
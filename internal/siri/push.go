@@ -0,0 +1,64 @@
+package siri
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// PushTarget is a consumer endpoint the client pushes producer messages like DataReadyNotifications to
+type PushTarget struct {
+	Name string
+	URL  string
+}
+
+// ParsePushTargets parses a comma separated list of targets like consumer=http://localhost:8000,other=http://other
+func ParsePushTargets(value string) ([]PushTarget, error) {
+	var targets []PushTarget
+	for entry := range strings.SplitSeq(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, targetURL, found := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		targetURL = strings.TrimSpace(targetURL)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid push target %q, use name=url", entry)
+		}
+		if parsed, err := url.Parse(targetURL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid URL of push target %s: %q", name, targetURL)
+		}
+		targets = append(targets, PushTarget{Name: name, URL: targetURL})
+	}
+	return targets, nil
+}
+
+// TemplateURL returns the URL a push template is sent to, the path comment of the template is appended to the URL
+func (t PushTarget) TemplateURL(template string) string {
+	return t.URL + GetURLPathFromTemplate(template)
+}
+
+// Push sends a producer message like a DataReadyNotification, HeartbeatNotification or ServiceDelivery
+// to a consumer. The body is executed as template. The headers, credentials and client certificate used
+// for the SIRI server are not sent, only the headers of the request. The trusted certificate authorities
+// and InsecureSkipVerify of the TLS settings are used.
+func (c *Client) Push(ctx context.Context, clientRequest ClientRequest) (ServerResponse, error) {
+	executedBody, err := executeTemplate(clientRequest.Body, data{ClientRef: c.settings().clientRef})
+	if err != nil {
+		return ServerResponse{}, err
+	}
+	return c.push(ctx, ClientRequest{URL: clientRequest.URL, Body: executedBody, Header: clientRequest.Header})
+}
+
+// push sends the body as it is to a consumer and marks the exchange as push
+func (c *Client) push(ctx context.Context, clientRequest ClientRequest) (ServerResponse, error) {
+	settings := c.settings()
+	pushClient := settings.httpclient
+	pushClient.SetAuthenticator(nil)
+	// a transport of its own, so the client certificate of the server is not presented to consumers
+	pushClient.SetTransport(settings.pushTransport)
+	clientRequest.Push = true
+	return c.post(ctx, pushClient, clientRequest)
+}
@@ -0,0 +1,174 @@
+package siri
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mszalbach/sirigo/internal/httputils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_push_targets_are_parsed(t *testing.T) {
+	testCases := map[string]struct {
+		value         string
+		expected      []PushTarget
+		expectedError string
+	}{
+		"empty": {"", nil, ""},
+		"targets": {
+			" consumer=http://localhost:9000/siri, other=https://other ",
+			[]PushTarget{{"consumer", "http://localhost:9000/siri"}, {"other", "https://other"}},
+			"",
+		},
+		"missing name": {"http://localhost:9000", nil, "invalid push target"},
+		"invalid url":  {"consumer=localhost", nil, "invalid URL of push target consumer"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// When
+			actual, err := ParsePushTargets(tc.value)
+
+			// Then
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func Test_push_target_url_uses_path_of_template(t *testing.T) {
+	// Given
+	target := PushTarget{Name: "consumer", URL: "http://consumer"}
+
+	// When
+	withPath := target.TemplateURL("<!-- path: /siri/et -->\n<Siri/>")
+	withoutPath := target.TemplateURL("<Siri/>")
+
+	// Then
+	assert.Equal(t, "http://consumer/siri/et", withPath)
+	assert.Equal(t, "http://consumer", withoutPath)
+}
+
+func Test_siri_client_pushes_without_server_credentials(t *testing.T) {
+	// Given
+	var received *http.Request
+	var body string
+	consumer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		content, _ := io.ReadAll(req.Body)
+		received, body = req, string(content)
+		rw.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(rw, "<Siri><DataReadyAcknowledgement/></Siri>")
+	}))
+	defer consumer.Close()
+	client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+	client.SetAuthenticator(httputils.BasicAuth{Username: "user", Password: "secret"})
+	client.Header = http.Header{"X-Api-Key": {"server"}}
+
+	// When
+	response, err := client.Push(t.Context(), ClientRequest{
+		URL:    consumer.URL + "/notify",
+		Body:   "<Siri><DataReadyNotification>{{ .ClientRef }}</DataReadyNotification></Siri>",
+		Header: http.Header{"X-Push": {"yes"}},
+	})
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Status)
+	assert.Equal(t, "<Siri><DataReadyNotification>CLIENT REF</DataReadyNotification></Siri>", body)
+	assert.Empty(t, received.Header.Get("Authorization"))
+	assert.Empty(t, received.Header.Get("X-Api-Key"))
+	assert.Equal(t, "yes", received.Header.Get("X-Push"))
+	exchanges := client.History.List()
+	require.Len(t, exchanges, 1)
+	assert.Equal(t, consumer.URL+"/notify", exchanges[0].URL)
+}
+
+// writeServerCertificate writes the certificate and key of the TLS test server into PEM files
+func writeServerCertificate(t *testing.T, server *httptest.Server) (certFile string, keyFile string) {
+	t.Helper()
+	certificate := server.TLS.Certificates[0]
+	key, err := x509.MarshalPKCS8PrivateKey(certificate.PrivateKey)
+	require.NoError(t, err)
+	certFile = filepath.Join(t.TempDir(), "cert.pem")
+	keyFile = filepath.Join(t.TempDir(), "key.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Certificate[0]})
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0o600))
+	return certFile, keyFile
+}
+
+func Test_siri_client_pushes_with_tls_settings_without_client_certificate(t *testing.T) {
+	testCases := map[string]struct {
+		trustCA  bool
+		insecure bool
+	}{
+		"trusted CA": {true, false},
+		"insecure":   {false, true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			var peerCertificates []*x509.Certificate
+			consumer := httptest.NewUnstartedServer(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+				peerCertificates = req.TLS.PeerCertificates
+			}))
+			consumer.TLS = &tls.Config{MinVersion: tls.VersionTLS12, ClientAuth: tls.RequestClientCert}
+			consumer.StartTLS()
+			defer consumer.Close()
+			// the certificate of the consumer is used as client certificate for the server as well
+			certFile, keyFile := writeServerCertificate(t, consumer)
+			tlsConfig := httputils.TLSConfig{CertFile: certFile, KeyFile: keyFile, InsecureSkipVerify: tc.insecure}
+			if tc.trustCA {
+				tlsConfig.CAFile = certFile
+			}
+			client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+			require.NoError(t, client.SetTLS(tlsConfig))
+
+			// When
+			response, err := client.Push(t.Context(), ClientRequest{URL: consumer.URL, Body: "<Siri/>"})
+
+			// Then
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, response.Status)
+			assert.Empty(t, peerCertificates)
+		})
+	}
+}
+
+func Test_replayed_push_is_sent_without_server_credentials(t *testing.T) {
+	// Given
+	var authorization []string
+	consumer := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		authorization = append(authorization, req.Header.Get("Authorization")+req.Header.Get("X-Api-Key"))
+	}))
+	defer consumer.Close()
+	client := NewClient("CLIENT REF", "SERVER URL", "CLIENT ADDRESS", io.Discard)
+	client.SetAuthenticator(httputils.BasicAuth{Username: "user", Password: "secret"})
+	client.Header = http.Header{"X-Api-Key": {"server"}}
+	_, err := client.Push(t.Context(), ClientRequest{URL: consumer.URL, Body: "<Siri/>"})
+	require.NoError(t, err)
+	pushed := client.History.List()[0]
+
+	// When
+	_, err = client.Replay(t.Context(), pushed.ClientRequest())
+
+	// Then
+	require.NoError(t, err)
+	assert.True(t, pushed.Push)
+	assert.Equal(t, []string{"", ""}, authorization)
+	exchanges := client.History.List()
+	require.Len(t, exchanges, 2)
+	assert.True(t, exchanges[1].Push)
+}
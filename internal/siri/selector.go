@@ -0,0 +1,264 @@
+package siri
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A selector is a small XPath-like expression to find elements in an XML body.
+// Supported are absolute (/Siri/ServiceDelivery) and descendant (//ServiceDelivery) steps,
+// the wildcard *, a trailing text() step and the predicates [text()='v'], [text()!='v'],
+// [@attribute='v'] and [Child='v'] (each also with !=).
+// Namespaces are ignored, only local names are compared.
+
+// xmlNode is a minimal DOM node used to evaluate selectors
+type xmlNode struct {
+	name     string
+	attrs    map[string]string
+	text     string
+	children []*xmlNode
+}
+
+type selectorStep struct {
+	descendant bool
+	name       string
+	predicates []selectorPredicate
+}
+
+type selectorPredicate struct {
+	// target is text(), @attribute or a child element name
+	target string
+	value  string
+	negate bool
+}
+
+// MatchesSelector reports whether the selector finds at least one element in the XML body
+func MatchesSelector(body string, selector string) (bool, error) {
+	nodes, err := selectNodes(body, selector)
+	if err != nil {
+		return false, err
+	}
+	return len(nodes) > 0, nil
+}
+
+// SelectText returns the text of the first element found by the selector in the XML body.
+// A trailing text() step is optional.
+func SelectText(body string, selector string) (string, error) {
+	nodes, err := selectNodes(body, selector)
+	if err != nil {
+		return "", err
+	}
+	if len(nodes) == 0 {
+		return "", nil
+	}
+	return nodes[0].text, nil
+}
+
+// MessageElementName returns the name of the message element of an XML body.
+// For SIRI this is the first element below <Siri>, for other XML bodies it is the root element.
+func MessageElementName(body string) string {
+	decoder := xml.NewDecoder(strings.NewReader(body))
+	isSiri := false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if isSiri || start.Name.Local != "Siri" {
+			return start.Name.Local
+		}
+		isSiri = true
+	}
+}
+
+func selectNodes(body string, selector string) ([]*xmlNode, error) {
+	steps, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	document, err := parseXMLNodes(body)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []*xmlNode{document}
+	for _, step := range steps {
+		var found []*xmlNode
+		for _, node := range nodes {
+			candidates := node.children
+			if step.descendant {
+				candidates = node.descendants()
+			}
+			for _, candidate := range candidates {
+				if step.matches(candidate) {
+					found = append(found, candidate)
+				}
+			}
+		}
+		nodes = found
+	}
+	return nodes, nil
+}
+
+func parseSelector(selector string) ([]selectorStep, error) {
+	rest := strings.TrimSpace(selector)
+	if !strings.HasPrefix(rest, "/") {
+		return nil, fmt.Errorf("selector %q must start with / or //", selector)
+	}
+
+	var steps []selectorStep
+	text := false
+	for rest != "" {
+		if text {
+			return nil, fmt.Errorf("selector %q: text() must be the last step", selector)
+		}
+		step := selectorStep{}
+		switch {
+		case strings.HasPrefix(rest, "//"):
+			step.descendant = true
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "/"):
+			rest = rest[1:]
+		default:
+			return nil, fmt.Errorf("selector %q: expected / at %q", selector, rest)
+		}
+
+		end := strings.IndexAny(rest, "/[")
+		if end == -1 {
+			end = len(rest)
+		}
+		step.name = rest[:end]
+		rest = rest[end:]
+		if step.name == "" {
+			return nil, fmt.Errorf("selector %q: missing element name", selector)
+		}
+
+		for strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("selector %q: missing ]", selector)
+			}
+			predicate, err := parsePredicate(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("selector %q: %w", selector, err)
+			}
+			step.predicates = append(step.predicates, predicate)
+			rest = rest[end+1:]
+		}
+
+		if step.name == "text()" {
+			text = true
+			continue
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func parsePredicate(expression string) (selectorPredicate, error) {
+	predicate := selectorPredicate{}
+	target, value, found := strings.Cut(expression, "!=")
+	if found {
+		predicate.negate = true
+	} else {
+		target, value, found = strings.Cut(expression, "=")
+	}
+	if !found {
+		return predicate, fmt.Errorf("unsupported predicate [%s]", expression)
+	}
+	predicate.target = strings.TrimSpace(target)
+	value = strings.TrimSpace(value)
+	if len(value) < 2 || (value[0] != '\'' && value[0] != '"') || value[len(value)-1] != value[0] {
+		return predicate, fmt.Errorf("value of predicate [%s] must be quoted", expression)
+	}
+	predicate.value = value[1 : len(value)-1]
+	return predicate, nil
+}
+
+func (s selectorStep) matches(node *xmlNode) bool {
+	if s.name != "*" && s.name != node.name {
+		return false
+	}
+	for _, predicate := range s.predicates {
+		if !predicate.matches(node) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p selectorPredicate) matches(node *xmlNode) bool {
+	var values []string
+	switch {
+	case p.target == "text()":
+		values = []string{node.text}
+	case strings.HasPrefix(p.target, "@"):
+		if value, ok := node.attrs[p.target[1:]]; ok {
+			values = []string{value}
+		}
+	default:
+		for _, child := range node.children {
+			if child.name == p.target {
+				values = append(values, child.text)
+			}
+		}
+	}
+
+	for _, value := range values {
+		if (value == p.value) != p.negate {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *xmlNode) descendants() []*xmlNode {
+	var nodes []*xmlNode
+	for _, child := range n.children {
+		nodes = append(nodes, child)
+		nodes = append(nodes, child.descendants()...)
+	}
+	return nodes
+}
+
+// parseXMLNodes parses the body into a document node which contains the root element as its only child
+func parseXMLNodes(body string) (*xmlNode, error) {
+	document := &xmlNode{}
+	stack := []*xmlNode{document}
+	decoder := xml.NewDecoder(strings.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		current := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local, attrs: map[string]string{}}
+			for _, attr := range t.Attr {
+				node.attrs[attr.Name.Local] = attr.Value
+			}
+			current.children = append(current.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			current.text = strings.TrimSpace(current.text)
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			current.text += string(t)
+		}
+	}
+	if len(document.children) == 0 {
+		return nil, errors.New("body contains no XML element")
+	}
+	return document, nil
+}
@@ -0,0 +1,106 @@
+package siri
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const selectorBody = `<?xml version="1.0" encoding="UTF-8"?>
+<Siri xmlns="http://www.siri.org.uk/siri" version="2.1">
+	<DataSupplyRequest>
+		<RequestTimestamp>2004-12-17T09:30:47Z</RequestTimestamp>
+		<ConsumerRef>slow</ConsumerRef>
+		<NotificationRef>ABCDE0</NotificationRef>
+	</DataSupplyRequest>
+</Siri>`
+
+func Test_matches_selectors(t *testing.T) {
+	testCases := map[string]struct {
+		selector string
+		expected bool
+	}{
+		"descendant element":             {"//DataSupplyRequest", true},
+		"missing descendant element":     {"//SubscriptionRequest", false},
+		"absolute path":                  {"/Siri/DataSupplyRequest/ConsumerRef", true},
+		"absolute path not from root":    {"/DataSupplyRequest", false},
+		"nested descendants":             {"//DataSupplyRequest//ConsumerRef", true},
+		"wildcard":                       {"/Siri/*/NotificationRef", true},
+		"text predicate":                 {"//DataSupplyRequest//ConsumerRef[text()='slow']", true},
+		"negated text predicate":         {"//DataSupplyRequest//ConsumerRef[text()!='slow']", false},
+		"attribute predicate":            {"/Siri[@version='2.1']", true},
+		"wrong attribute predicate":      {"/Siri[@version='2.0']", false},
+		"child predicate":                {"//DataSupplyRequest[ConsumerRef='slow']", true},
+		"negated child predicate":        {"//DataSupplyRequest[ConsumerRef!='fast']", true},
+		"multiple predicates":            {"//DataSupplyRequest[ConsumerRef='slow'][NotificationRef='X']", false},
+		"trailing text() is allowed":     {"//ConsumerRef/text()", true},
+		"double quoted predicate values": {`//ConsumerRef[text()="slow"]`, true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual, err := MatchesSelector(selectorBody, tc.selector)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func Test_invalid_selectors_return_errors(t *testing.T) {
+	testCases := map[string]string{
+		"relative selector":      "DataSupplyRequest",
+		"missing element name":   "/Siri//",
+		"unclosed predicate":     "//ConsumerRef[text()='slow'",
+		"unquoted value":         "//ConsumerRef[text()=slow]",
+		"unsupported predicate":  "//ConsumerRef[1]",
+		"text() not as last one": "//ConsumerRef/text()/Test",
+	}
+	for name, selector := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := MatchesSelector(selectorBody, selector)
+			require.Error(t, err)
+		})
+	}
+}
+
+func Test_selector_fails_for_non_xml_bodies(t *testing.T) {
+	_, err := MatchesSelector("no xml", "//Siri")
+	require.Error(t, err)
+}
+
+func Test_selects_text(t *testing.T) {
+	testCases := map[string]struct {
+		selector string
+		expected string
+	}{
+		"with text()":        {"/Siri/DataSupplyRequest/NotificationRef/text()", "ABCDE0"},
+		"without text()":     {"//ConsumerRef", "slow"},
+		"not found":          {"//SubscriptionIdentifier/text()", ""},
+		"element with child": {"//DataSupplyRequest/RequestTimestamp", "2004-12-17T09:30:47Z"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual, err := SelectText(selectorBody, tc.selector)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func Test_returns_message_element_name(t *testing.T) {
+	testCases := map[string]struct {
+		body     string
+		expected string
+	}{
+		"SIRI message":           {selectorBody, "DataSupplyRequest"},
+		"VDV message":            {`<?xml version="1.0"?><DatenBereitAnfrage Sender="S"/>`, "DatenBereitAnfrage"},
+		"empty Siri":             {"<Siri></Siri>", ""},
+		"no xml":                 {"Hello", ""},
+		"leading comment in xml": {"<!-- path: /siri --><Siri><CheckStatusRequest/></Siri>", "CheckStatusRequest"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, MessageElementName(tc.body))
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package siri
+
+import (
+	"cmp"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mszalbach/sirigo/internal/httputils"
+)
+
+// ServerSubscription is a subscription a consumer requested from the Server
+type ServerSubscription struct {
+	Identifier    string
+	RequestorRef  string
+	SubscriberRef string
+	ServiceType   string
+	// ConsumerAddress is where DataReadyNotifications are sent to, empty if the consumer did not send one
+	ConsumerAddress string
+	// Path is the URL path the subscription was requested at
+	Path                   string
+	InitialTerminationTime time.Time
+}
+
+// Expired reports whether the InitialTerminationTime of the subscription has passed
+func (s ServerSubscription) Expired(now time.Time) bool {
+	return !s.InitialTerminationTime.IsZero() && now.After(s.InitialTerminationTime)
+}
+
+// Server simulates a SIRI producer. It accepts subscriptions, sends DataReadyNotifications to the subscribers
+// and answers their DataSupplyRequests with delivery templates.
+type Server struct {
+	ProducerRef   string
+	deliveries    TemplateCache
+	mu            sync.Mutex
+	subscriptions []ServerSubscription
+	// mappings answer matching requests before the simulation
+	mappings   []Mapping
+	startTime  time.Time
+	httpclient httputils.LoggingClient
+	httpserver *httputils.LoggingMuxServer
+}
+
+// NewServer creates a Server listening on address which answers DataSupplyRequests with the delivery templates
+func NewServer(producerRef string, address string, deliveries TemplateCache, requestLogging io.Writer) *Server {
+	return &Server{
+		ProducerRef: producerRef,
+		deliveries:  deliveries,
+		startTime:   time.Now(),
+		httpclient:  httputils.NewLoggingClient(requestLogging),
+		httpserver:  httputils.NewLoggingMuxServer(address, requestLogging),
+	}
+}
+
+// Subscriptions returns a copy of all subscriptions in the order they were requested
+func (s *Server) Subscriptions() []ServerSubscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.subscriptions)
+}
+
+// SetMappings sets the mock response rules. The first matching mapping answers a request instead of the simulation,
+// subscriptions are remembered anyway.
+func (s *Server) SetMappings(mappings []Mapping) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mappings = slices.Clone(mappings)
+}
+
+// ListenAndServe starts the HTTP server which answers the requests of the consumers
+func (s *Server) ListenAndServe() error {
+	_ = s.createHandler()
+	return s.httpserver.ListenAndServe()
+}
+
+func (s *Server) createHandler() http.Handler {
+	s.httpserver.HandleFunc("/", s.handleRequests)
+	return s.httpserver.Handler
+}
+
+// Stop stops the HTTP server for the given context
+func (s *Server) Stop(ctx context.Context) error {
+	return s.httpserver.Shutdown(ctx)
+}
+
+// RunDataReady sends DataReadyNotifications every interval until the context is canceled
+func (s *Server) RunDataReady(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.NotifyDataReady(ctx)
+		}
+	}
+}
+
+// NotifyDataReady sends a DataReadyNotification to the address of every consumer with subscriptions
+func (s *Server) NotifyDataReady(ctx context.Context) {
+	body, err := Message{
+		DataReadyNotification: &DataReadyNotification{
+			RequestTimestamp: DateTime{Time: time.Now().UTC()},
+			ProducerRef:      s.ProducerRef,
+		},
+	}.Marshal()
+	if err != nil {
+		slog.Error("Could not create DataReadyNotification", slog.Any("error", err))
+		return
+	}
+	for _, address := range s.consumerAddresses(time.Now()) {
+		response, err := s.httpclient.PostXML(ctx, address, body, nil)
+		if err != nil {
+			slog.Warn("Could not send DataReadyNotification", slog.String("address", address), slog.Any("error", err))
+			continue
+		}
+		slog.Info(
+			"Sent DataReadyNotification",
+			slog.String("address", address),
+			slog.Int("status", response.StatusCode),
+		)
+	}
+}
+
+// consumerAddresses returns the addresses of all consumers with subscriptions, expired subscriptions are removed
+func (s *Server) consumerAddresses(now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscriptions = slices.DeleteFunc(s.subscriptions, func(subscription ServerSubscription) bool {
+		return subscription.Expired(now)
+	})
+	var addresses []string
+	for _, subscription := range s.subscriptions {
+		if subscription.ConsumerAddress != "" && !slices.Contains(addresses, subscription.ConsumerAddress) {
+			addresses = append(addresses, subscription.ConsumerAddress)
+		}
+	}
+	return addresses
+}
+
+func (s *Server) handleRequests(w http.ResponseWriter, r *http.Request) {
+	bytesBody, err := io.ReadAll(r.Body)
+	if err != nil {
+		slog.Error("Could not read request body", slog.Any("error", err))
+		http.Error(w, "Could not read request body", http.StatusInternalServerError)
+		return
+	}
+	s.mu.Lock()
+	mapping, found := findMapping(s.mappings, r.Method, r.URL.RequestURI(), string(bytesBody))
+	s.mu.Unlock()
+	if found {
+		s.respondWithMapping(w, r, mapping, string(bytesBody))
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST requests are supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status, body, err := s.respond(r.URL.Path, TryParseMessage(string(bytesBody)))
+	if err != nil {
+		slog.Warn("Could not answer consumer request", slog.String("path", r.URL.Path), slog.Any("error", err))
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set(httputils.HeaderContentType, httputils.ContentTypeXML)
+	w.WriteHeader(status)
+	fmt.Fprint(w, body)
+}
+
+// respondWithMapping sends the response of the mapping after its delay.
+// Subscriptions and terminations are remembered so DataReadyNotifications are sent like without the mapping.
+func (s *Server) respondWithMapping(w http.ResponseWriter, r *http.Request, mapping Mapping, body string) {
+	now := time.Now().UTC()
+	if request := TryParseMessage(body); request != nil {
+		switch {
+		case request.SubscriptionRequest != nil:
+			s.subscribe(r.URL.Path, request.SubscriptionRequest, now)
+		case request.TerminateSubscriptionRequest != nil:
+			s.terminate(request.TerminateSubscriptionRequest, now)
+		}
+	}
+	select {
+	case <-r.Context().Done():
+		return
+	case <-time.After(mapping.Delay()):
+	}
+	for name, value := range mapping.Response.Headers {
+		w.Header().Set(name, value)
+	}
+	w.WriteHeader(mapping.Response.Status)
+	fmt.Fprint(w, renderMappingBody(mapping.Response.Body, body, time.Now()))
+}
+
+// respond creates the answer to a request of a consumer sent to path
+func (s *Server) respond(path string, request *Message) (int, string, error) {
+	now := time.Now().UTC()
+	var response Message
+	switch {
+	case request == nil:
+		return http.StatusBadRequest, "", errors.New("request is not a SIRI message")
+	case request.SubscriptionRequest != nil:
+		response.SubscriptionResponse = s.subscribe(path, request.SubscriptionRequest, now)
+	case request.TerminateSubscriptionRequest != nil:
+		response.TerminateSubscriptionResponse = s.terminate(request.TerminateSubscriptionRequest, now)
+	case request.CheckStatusRequest != nil:
+		started := DateTime{Time: s.startTime.UTC()}
+		response.CheckStatusResponse = &CheckStatusResponse{
+			ResponseTimestamp:  DateTime{Time: now},
+			ProducerRef:        s.ProducerRef,
+			Status:             true,
+			ServiceStartedTime: &started,
+		}
+	case request.DataSupplyRequest != nil:
+		body, err := s.deliver(path, request.DataSupplyRequest, now)
+		if err != nil {
+			return http.StatusInternalServerError, "", err
+		}
+		return http.StatusOK, body, nil
+	default:
+		return http.StatusBadRequest, "", errors.New("unsupported SIRI request")
+	}
+	body, err := response.Marshal()
+	if err != nil {
+		return http.StatusInternalServerError, "", err
+	}
+	return http.StatusOK, body, nil
+}
+
+// subscribe accepts all service subscriptions of the request
+func (s *Server) subscribe(path string, request *SubscriptionRequest, now time.Time) *SubscriptionResponse {
+	response := &SubscriptionResponse{
+		ResponseTimestamp: DateTime{Time: now},
+		ResponderRef:      s.ProducerRef,
+		RequestMessageRef: request.MessageIdentifier,
+	}
+	consumerAddress := cmp.Or(request.ConsumerAddress, request.Address)
+	if consumerAddress == "" {
+		slog.Warn(
+			"SubscriptionRequest without ConsumerAddress or Address, no DataReadyNotifications are sent",
+			slog.String("requestorRef", request.RequestorRef),
+		)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, serviceRequest := range request.Subscriptions {
+		service, found := strings.CutSuffix(serviceRequest.XMLName.Local, "SubscriptionRequest")
+		if !found {
+			continue
+		}
+		subscription := ServerSubscription{
+			Identifier:             serviceRequest.SubscriptionIdentifier,
+			RequestorRef:           request.RequestorRef,
+			SubscriberRef:          cmp.Or(serviceRequest.SubscriberRef, request.RequestorRef),
+			ServiceType:            serviceType(service),
+			ConsumerAddress:        consumerAddress,
+			Path:                   path,
+			InitialTerminationTime: serviceRequest.InitialTerminationTime.Time,
+		}
+		s.put(subscription)
+
+		status := ResponseStatus{
+			ResponseTimestamp: DateTime{Time: now},
+			RequestMessageRef: request.MessageIdentifier,
+			SubscriberRef:     subscription.SubscriberRef,
+			SubscriptionRef:   subscription.Identifier,
+			Status:            true,
+		}
+		if !subscription.InitialTerminationTime.IsZero() {
+			status.ValidUntil = &DateTime{Time: subscription.InitialTerminationTime}
+		}
+		response.ResponseStatus = append(response.ResponseStatus, status)
+	}
+	return response
+}
+
+// put adds the subscription or replaces an existing one with the same requestor and identifier
+func (s *Server) put(subscription ServerSubscription) {
+	for i, existing := range s.subscriptions {
+		if existing.Identifier == subscription.Identifier && existing.RequestorRef == subscription.RequestorRef {
+			s.subscriptions[i] = subscription
+			return
+		}
+	}
+	s.subscriptions = append(s.subscriptions, subscription)
+}
+
+// terminate removes the requested subscriptions of the requestor, unknown subscriptions are reported as failed
+func (s *Server) terminate(request *TerminateSubscriptionRequest, now time.Time) *TerminateSubscriptionResponse {
+	response := &TerminateSubscriptionResponse{ResponseTimestamp: DateTime{Time: now}, ResponderRef: s.ProducerRef}
+	terminated := func(subscription ServerSubscription) bool {
+		return subscription.RequestorRef == request.RequestorRef &&
+			(request.All != nil || slices.Contains(request.SubscriptionRefs, subscription.Identifier))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var found []string
+	for _, subscription := range s.subscriptions {
+		if terminated(subscription) {
+			found = append(found, subscription.Identifier)
+			response.TerminationResponseStatus = append(response.TerminationResponseStatus, TerminationResponseStatus{
+				ResponseTimestamp: DateTime{Time: now},
+				SubscriberRef:     subscription.SubscriberRef,
+				SubscriptionRef:   subscription.Identifier,
+				Status:            true,
+			})
+		}
+	}
+	for _, ref := range request.SubscriptionRefs {
+		if !slices.Contains(found, ref) {
+			response.TerminationResponseStatus = append(response.TerminationResponseStatus, TerminationResponseStatus{
+				ResponseTimestamp: DateTime{Time: now},
+				SubscriptionRef:   ref,
+				ErrorCondition:    errorCondition("UnknownSubscriptionError", "unknown subscription "+ref),
+			})
+		}
+	}
+	s.subscriptions = slices.DeleteFunc(s.subscriptions, terminated)
+	return response
+}
+
+// deliver executes the delivery template of the service the consumer subscribed at path.
+// The template is named after the service type like et.xml.
+// A ServiceDelivery with an ErrorCondition is returned if there is no subscription or no template.
+func (s *Server) deliver(path string, request *DataSupplyRequest, now time.Time) (string, error) {
+	subscription, ok := s.findSubscription(request.ConsumerRef, path)
+	if !ok {
+		return s.failedDelivery(now, errorCondition(
+			"UnknownSubscriberError",
+			fmt.Sprintf("no subscription of %s at %s", request.ConsumerRef, path),
+		))
+	}
+	name := strings.ToLower(subscription.ServiceType) + ".xml"
+	template, err := s.deliveries.GetTemplate(name)
+	if err != nil {
+		slog.Warn("Could not read delivery template", slog.String("template", name), slog.Any("error", err))
+		return s.failedDelivery(now, errorCondition("ServiceNotAvailableError", "no delivery template "+name))
+	}
+	return executeTemplate(template, data{
+		ProducerRef:     s.ProducerRef,
+		ConsumerRef:     request.ConsumerRef,
+		SubscriberRef:   subscription.SubscriberRef,
+		SubscriptionRef: subscription.Identifier,
+	})
+}
+
+// findSubscription returns the first subscription of the consumer, subscriptions at path are preferred
+func (s *Server) findSubscription(consumerRef string, path string) (ServerSubscription, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var candidates []ServerSubscription
+	for _, subscription := range s.subscriptions {
+		if subscription.RequestorRef == consumerRef || subscription.SubscriberRef == consumerRef {
+			candidates = append(candidates, subscription)
+		}
+	}
+	for _, subscription := range candidates {
+		if subscription.Path == path {
+			return subscription, true
+		}
+	}
+	if len(candidates) == 0 {
+		return ServerSubscription{}, false
+	}
+	return candidates[0], true
+}
+
+func (s *Server) failedDelivery(now time.Time, condition *ErrorCondition) (string, error) {
+	status := false
+	return Message{
+		ServiceDelivery: &ServiceDelivery{
+			ResponseTimestamp: DateTime{Time: now},
+			ProducerRef:       s.ProducerRef,
+			Status:            &status,
+			ErrorCondition:    condition,
+		},
+	}.Marshal()
+}
+
+func errorCondition(name string, text string) *ErrorCondition {
+	return &ErrorCondition{Errors: []ErrorDetail{{XMLName: xml.Name{Local: name}, ErrorText: text}}}
+}
@@ -0,0 +1,242 @@
+package siri
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const etDeliveryTemplate = `<Siri xmlns="http://www.siri.org.uk/siri" version="2.1">
+	<ServiceDelivery>
+		<ResponseTimestamp>{{ dateTime .Now }}</ResponseTimestamp>
+		<ProducerRef>{{ .ProducerRef }}</ProducerRef>
+		<EstimatedTimetableDelivery>
+			<ResponseTimestamp>{{ dateTime .Now }}</ResponseTimestamp>
+			<SubscriberRef>{{ .SubscriberRef }}</SubscriberRef>
+			<SubscriptionRef>{{ .SubscriptionRef }}</SubscriptionRef>
+			<Status>true</Status>
+		</EstimatedTimetableDelivery>
+	</ServiceDelivery>
+</Siri>`
+
+func newTestServer(t *testing.T) (*Server, http.Handler) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "et.xml"), []byte(etDeliveryTemplate), 0o600))
+	deliveries, err := NewTemplateCache(dir)
+	require.NoError(t, err)
+	server := NewServer("producer", "SERVER ADDRESS", deliveries, io.Discard)
+	return server, server.createHandler()
+}
+
+// post sends the body to the handler and returns the parsed response
+func post(t *testing.T, handler http.Handler, path string, body string) (int, *Message) {
+	t.Helper()
+	request, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	return response.Code, TryParseMessage(response.Body.String())
+}
+
+func subscriptionRequest(consumerAddress string, terminationTime time.Time) string {
+	return `<Siri><SubscriptionRequest>
+	<RequestorRef>consumer</RequestorRef>
+	<ConsumerAddress>` + consumerAddress + `</ConsumerAddress>
+	<EstimatedTimetableSubscriptionRequest>
+		<SubscriptionIdentifier>1</SubscriptionIdentifier>
+		<InitialTerminationTime>` + terminationTime.Format(time.RFC3339) + `</InitialTerminationTime>
+	</EstimatedTimetableSubscriptionRequest>
+</SubscriptionRequest></Siri>`
+}
+
+func Test_server_accepts_subscriptions(t *testing.T) {
+	// Given
+	server, handler := newTestServer(t)
+	terminationTime := time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// When
+	status, response := post(t, handler, "/et", subscriptionRequest("http://consumer/siri", terminationTime))
+
+	// Then
+	assert.Equal(t, http.StatusOK, status)
+	require.NotNil(t, response.SubscriptionResponse)
+	ok, found := response.Status()
+	assert.True(t, ok)
+	assert.True(t, found)
+	assert.Equal(t, "1", response.SubscriptionResponse.ResponseStatus[0].SubscriptionRef)
+	assert.Equal(t, []ServerSubscription{{
+		Identifier:             "1",
+		RequestorRef:           "consumer",
+		SubscriberRef:          "consumer",
+		ServiceType:            "ET",
+		ConsumerAddress:        "http://consumer/siri",
+		Path:                   "/et",
+		InitialTerminationTime: terminationTime,
+	}}, server.Subscriptions())
+}
+
+func Test_server_answers_data_supply_requests_with_delivery_templates(t *testing.T) {
+	testCases := map[string]struct {
+		consumerRef     string
+		expectedStatus  bool
+		expectedContent string
+	}{
+		"subscribed consumer": {"consumer", true, "<SubscriptionRef>1</SubscriptionRef>"},
+		"unknown consumer":    {"unknown", false, "UnknownSubscriberError"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			_, handler := newTestServer(t)
+			post(t, handler, "/et", subscriptionRequest("http://consumer/siri", time.Now().Add(time.Hour)))
+			body := `<Siri><DataSupplyRequest><ConsumerRef>` + tc.consumerRef + `</ConsumerRef></DataSupplyRequest></Siri>`
+			request, _ := http.NewRequest(http.MethodPost, "/et", strings.NewReader(body))
+			response := httptest.NewRecorder()
+
+			// When
+			handler.ServeHTTP(response, request)
+
+			// Then
+			assert.Equal(t, http.StatusOK, response.Code)
+			assert.Contains(t, response.Body.String(), tc.expectedContent)
+			message := TryParseMessage(response.Body.String())
+			require.NotNil(t, message.ServiceDelivery)
+			assert.Equal(t, "producer", message.ServiceDelivery.ProducerRef)
+			ok, _ := message.Status()
+			assert.Equal(t, tc.expectedStatus, ok)
+		})
+	}
+}
+
+func Test_server_terminates_subscriptions(t *testing.T) {
+	// Given
+	server, handler := newTestServer(t)
+	post(t, handler, "/et", subscriptionRequest("http://consumer/siri", time.Now().Add(time.Hour)))
+
+	// When
+	status, response := post(t, handler, "/et", `<Siri><TerminateSubscriptionRequest>
+	<RequestorRef>consumer</RequestorRef>
+	<SubscriptionRef>1</SubscriptionRef>
+	<SubscriptionRef>2</SubscriptionRef>
+</TerminateSubscriptionRequest></Siri>`)
+
+	// Then
+	assert.Equal(t, http.StatusOK, status)
+	require.NotNil(t, response.TerminateSubscriptionResponse)
+	statuses := response.TerminateSubscriptionResponse.TerminationResponseStatus
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Status)
+	assert.False(t, statuses[1].Status)
+	assert.Equal(t, "unknown subscription 2", statuses[1].ErrorCondition.String())
+	assert.Empty(t, server.Subscriptions())
+}
+
+func Test_server_sends_data_ready_to_subscribers(t *testing.T) {
+	// Given
+	notifications := make(chan *Message, 2)
+	consumer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		notifications <- TryParseMessage(string(body))
+	}))
+	defer consumer.Close()
+	server, handler := newTestServer(t)
+	post(t, handler, "/et", subscriptionRequest(consumer.URL, time.Now().Add(time.Hour)))
+	post(t, handler, "/vm", strings.Replace(
+		subscriptionRequest("http://expired", time.Now().Add(-time.Hour)),
+		"<RequestorRef>consumer</RequestorRef>",
+		"<RequestorRef>expired</RequestorRef>",
+		1,
+	))
+
+	// When
+	server.NotifyDataReady(t.Context())
+
+	// Then
+	require.Len(t, notifications, 1)
+	notification := <-notifications
+	require.NotNil(t, notification.DataReadyNotification)
+	assert.Equal(t, "producer", notification.DataReadyNotification.ProducerRef)
+	assert.Len(t, server.Subscriptions(), 1)
+}
+
+func Test_server_sends_data_ready_to_address_without_consumer_address(t *testing.T) {
+	// Given
+	server, handler := newTestServer(t)
+	request := strings.Replace(
+		subscriptionRequest("", time.Now().Add(time.Hour)),
+		"<RequestorRef>consumer</RequestorRef>",
+		"<Address>http://consumer/siri</Address><RequestorRef>consumer</RequestorRef>",
+		1,
+	)
+
+	// When
+	status, _ := post(t, handler, "/et", request)
+
+	// Then
+	assert.Equal(t, http.StatusOK, status)
+	require.Len(t, server.Subscriptions(), 1)
+	assert.Equal(t, "http://consumer/siri", server.Subscriptions()[0].ConsumerAddress)
+}
+
+func Test_client_subscribed_with_shipped_template_receives_data_ready(t *testing.T) {
+	// Given
+	server, handler := newTestServer(t)
+	producer := httptest.NewServer(handler)
+	defer producer.Close()
+	client := NewClient("sirigo", producer.URL, "", io.Discard)
+	client.SetFetchedMode(true)
+	consumer := httptest.NewServer(client.createHandler())
+	defer consumer.Close()
+	client.ConsumerAddress = consumer.URL
+	templates, err := NewTemplateCache("../../templates/siri/request")
+	require.NoError(t, err)
+	template, err := templates.GetTemplate("et/estimatedTimetable_subscriptionRequest.xml")
+	require.NoError(t, err)
+	_, err = client.Send(t.Context(), ClientRequest{
+		URL:  client.TemplateURL("et/estimatedTimetable_subscriptionRequest.xml", template),
+		Body: template,
+	})
+	require.NoError(t, err)
+
+	// When
+	server.NotifyDataReady(t.Context())
+
+	// Then
+	select {
+	case request := <-client.ServerRequest:
+		require.NotNil(t, request.Message)
+		assert.NotNil(t, request.Message.DataReadyNotification)
+	case <-time.After(time.Second):
+		assert.Fail(t, "no DataReadyNotification received")
+	}
+	select {
+	case response := <-client.FetchedResponse:
+		require.NotNil(t, response.Message)
+		require.NotNil(t, response.Message.ServiceDelivery)
+		assert.Equal(t, "producer", response.Message.ServiceDelivery.ProducerRef)
+	case <-time.After(time.Second):
+		assert.Fail(t, "no data fetched")
+	}
+}
+
+func Test_server_rejects_unsupported_requests(t *testing.T) {
+	// Given
+	_, handler := newTestServer(t)
+
+	// When
+	noSiri, _ := post(t, handler, "/", "no xml")
+	unsupported, _ := post(t, handler, "/", "<Siri><HeartbeatNotification/></Siri>")
+
+	// Then
+	assert.Equal(t, http.StatusBadRequest, noSiri)
+	assert.Equal(t, http.StatusBadRequest, unsupported)
+}
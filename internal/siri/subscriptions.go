@@ -0,0 +1,262 @@
+package siri
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SubscriptionStatus describes the state of a subscription from the client point of view
+type SubscriptionStatus string
+
+const (
+	// SubscriptionRequested is used when the SubscriptionRequest was sent but the server did not answer it (yet)
+	SubscriptionRequested SubscriptionStatus = "requested"
+	// SubscriptionActive is used when the server accepted the subscription
+	SubscriptionActive SubscriptionStatus = "active"
+	// SubscriptionRejected is used when the server rejected the subscription
+	SubscriptionRejected SubscriptionStatus = "rejected"
+	// SubscriptionTerminated is used when the subscription was terminated by a TerminateSubscriptionRequest
+	SubscriptionTerminated SubscriptionStatus = "terminated"
+)
+
+// serviceTypes maps the SIRI service names to their common abbreviations
+var serviceTypes = map[string]string{
+	"EstimatedTimetable":   "ET",
+	"ProductionTimetable":  "PT",
+	"StopTimetable":        "ST",
+	"StopMonitoring":       "SM",
+	"VehicleMonitoring":    "VM",
+	"ConnectionTimetable":  "CT",
+	"ConnectionMonitoring": "CM",
+	"GeneralMessage":       "GM",
+	"FacilityMonitoring":   "FM",
+	"SituationExchange":    "SX",
+}
+
+// Subscription is a subscription the client requested from a SIRI server
+type Subscription struct {
+	Identifier             string
+	SubscriberRef          string
+	ServiceType            string
+	URL                    string
+	InitialTerminationTime time.Time
+	Status                 SubscriptionStatus
+	ErrorText              string
+	// HeartbeatInterval is the interval of the HeartbeatNotifications requested in the SubscriptionContext
+	HeartbeatInterval time.Duration
+	// ProducerRef is the ResponderRef of the SubscriptionResponse, empty if the server did not send one
+	ProducerRef string
+	// Deliveries is the number of ServiceDeliveries received in direct delivery mode
+	Deliveries   int
+	LastDelivery time.Time
+}
+
+// Expired reports whether the InitialTerminationTime of an active subscription has passed
+func (s Subscription) Expired(now time.Time) bool {
+	return s.Status == SubscriptionActive && !s.InitialTerminationTime.IsZero() && now.After(s.InitialTerminationTime)
+}
+
+// Subscriptions keeps track of all subscriptions sent by a client
+type Subscriptions struct {
+	// Changed receives a value whenever the subscriptions changed
+	Changed       <-chan struct{}
+	changedWriter chan struct{}
+	mu            sync.Mutex
+	subscriptions []Subscription
+}
+
+// NewSubscriptions creates an empty subscription registry
+func NewSubscriptions() *Subscriptions {
+	changed := make(chan struct{}, 1)
+	return &Subscriptions{
+		Changed:       changed,
+		changedWriter: changed,
+	}
+}
+
+// List returns a copy of all tracked subscriptions in the order they were requested
+func (s *Subscriptions) List() []Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.subscriptions)
+}
+
+// track updates the subscriptions based on a request sent to url and the response of the server
+func (s *Subscriptions) track(url string, request *Message, response *Message) {
+	if request == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case request.SubscriptionRequest != nil:
+		s.subscribe(url, request.SubscriptionRequest, response)
+	case request.TerminateSubscriptionRequest != nil:
+		s.terminate(request.TerminateSubscriptionRequest, response)
+	default:
+		return
+	}
+	s.notify()
+}
+
+func (s *Subscriptions) subscribe(url string, request *SubscriptionRequest, response *Message) {
+	var statuses []ResponseStatus
+	var producerRef string
+	if response != nil && response.SubscriptionResponse != nil {
+		statuses = response.SubscriptionResponse.ResponseStatus
+		producerRef = response.SubscriptionResponse.ResponderRef
+	}
+
+	heartbeatInterval := requestedHeartbeatInterval(request)
+	for _, serviceRequest := range request.Subscriptions {
+		service, found := strings.CutSuffix(serviceRequest.XMLName.Local, "SubscriptionRequest")
+		if !found {
+			continue
+		}
+		subscription := Subscription{
+			Identifier:             serviceRequest.SubscriptionIdentifier,
+			SubscriberRef:          cmp.Or(serviceRequest.SubscriberRef, request.RequestorRef),
+			ServiceType:            serviceType(service),
+			URL:                    url,
+			InitialTerminationTime: serviceRequest.InitialTerminationTime.Time,
+			HeartbeatInterval:      heartbeatInterval,
+			ProducerRef:            producerRef,
+			Status:                 SubscriptionRequested,
+		}
+		// a single status without reference is interpreted as the status for the only subscription
+		if status, ok := findResponseStatus(statuses, subscription, len(request.Subscriptions) == 1); ok {
+			subscription.Status = SubscriptionActive
+			if !status.Status {
+				subscription.Status = SubscriptionRejected
+				subscription.ErrorText = status.ErrorCondition.String()
+			}
+		}
+		s.put(subscription)
+	}
+}
+
+func (s *Subscriptions) terminate(request *TerminateSubscriptionRequest, response *Message) {
+	if response == nil || response.TerminateSubscriptionResponse == nil {
+		return
+	}
+	statuses := response.TerminateSubscriptionResponse.TerminationResponseStatus
+	allTerminated := len(statuses) > 0
+	for _, status := range statuses {
+		allTerminated = allTerminated && status.Status
+		if !status.Status {
+			continue
+		}
+		for i, subscription := range s.subscriptions {
+			if subscription.Identifier == status.SubscriptionRef &&
+				(status.SubscriberRef == "" || subscription.SubscriberRef == status.SubscriberRef) {
+				s.subscriptions[i].Status = SubscriptionTerminated
+			}
+		}
+	}
+
+	if request.All != nil && allTerminated {
+		for i, subscription := range s.subscriptions {
+			if subscription.SubscriberRef == request.RequestorRef {
+				s.subscriptions[i].Status = SubscriptionTerminated
+			}
+		}
+	}
+}
+
+// deliver attaches a directly delivered ServiceDelivery to the subscription it was sent for.
+// subscribed is false if no requested or active subscription matches,
+// terminated is true if only a terminated subscription matches.
+func (s *Subscriptions) deliver(subscriberRef string, subscriptionRef string, at time.Time) (bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	terminated := false
+	for i, subscription := range s.subscriptions {
+		if subscription.Identifier != subscriptionRef ||
+			(subscriberRef != "" && subscription.SubscriberRef != subscriberRef) {
+			continue
+		}
+		if subscription.Status != SubscriptionRequested && subscription.Status != SubscriptionActive {
+			terminated = terminated || subscription.Status == SubscriptionTerminated
+			continue
+		}
+		s.subscriptions[i].Deliveries++
+		s.subscriptions[i].LastDelivery = at
+		s.notify()
+		return true, false
+	}
+	return false, terminated
+}
+
+// heartbeatIntervals returns the shortest HeartbeatInterval of the requested and active subscriptions
+// which are not expired by their ProducerRef. Producers without such subscriptions are not included.
+func (s *Subscriptions) heartbeatIntervals(now time.Time) map[string]time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	intervals := map[string]time.Duration{}
+	for _, subscription := range s.subscriptions {
+		if subscription.HeartbeatInterval <= 0 || subscription.Expired(now) ||
+			(subscription.Status != SubscriptionRequested && subscription.Status != SubscriptionActive) {
+			continue
+		}
+		if interval, ok := intervals[subscription.ProducerRef]; !ok || subscription.HeartbeatInterval < interval {
+			intervals[subscription.ProducerRef] = subscription.HeartbeatInterval
+		}
+	}
+	return intervals
+}
+
+// put adds the subscription or replaces an existing one with the same subscriber and identifier
+func (s *Subscriptions) put(subscription Subscription) {
+	for i, existing := range s.subscriptions {
+		if existing.Identifier == subscription.Identifier && existing.SubscriberRef == subscription.SubscriberRef {
+			s.subscriptions[i] = subscription
+			return
+		}
+	}
+	s.subscriptions = append(s.subscriptions, subscription)
+}
+
+// notify informs listeners without blocking if nobody is listening
+func (s *Subscriptions) notify() {
+	select {
+	case s.changedWriter <- struct{}{}:
+	default:
+	}
+}
+
+func findResponseStatus(statuses []ResponseStatus, subscription Subscription, single bool) (ResponseStatus, bool) {
+	for _, status := range statuses {
+		if status.SubscriptionRef == subscription.Identifier {
+			return status, true
+		}
+	}
+	if single && len(statuses) == 1 && statuses[0].SubscriptionRef == "" {
+		return statuses[0], true
+	}
+	return ResponseStatus{}, false
+}
+
+// requestedHeartbeatInterval returns the HeartbeatInterval of the SubscriptionContext, 0 if none is set
+func requestedHeartbeatInterval(request *SubscriptionRequest) time.Duration {
+	if request.SubscriptionContext == nil || request.SubscriptionContext.HeartbeatInterval == "" {
+		return 0
+	}
+	interval, err := parseXSDDuration(strings.TrimSpace(request.SubscriptionContext.HeartbeatInterval))
+	if err != nil {
+		slog.Warn("Invalid HeartbeatInterval in SubscriptionRequest", slog.Any("error", err))
+		return 0
+	}
+	return interval
+}
+
+func serviceType(service string) string {
+	if abbreviation, ok := serviceTypes[service]; ok {
+		return abbreviation
+	}
+	return service
+}
@@ -0,0 +1,182 @@
+package siri
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const etSubscriptionRequest = `<Siri xmlns="http://www.siri.org.uk/siri" version="2.1">
+	<SubscriptionRequest>
+		<RequestTimestamp>2004-12-17T09:30:47Z</RequestTimestamp>
+		<RequestorRef>client</RequestorRef>
+		<EstimatedTimetableSubscriptionRequest>
+			<SubscriberRef>client</SubscriberRef>
+			<SubscriptionIdentifier>1</SubscriptionIdentifier>
+			<InitialTerminationTime>2004-12-17T11:30:47Z</InitialTerminationTime>
+		</EstimatedTimetableSubscriptionRequest>
+		<VehicleMonitoringSubscriptionRequest>
+			<SubscriptionIdentifier>2</SubscriptionIdentifier>
+			<InitialTerminationTime>2004-12-17T11:30:47Z</InitialTerminationTime>
+		</VehicleMonitoringSubscriptionRequest>
+	</SubscriptionRequest>
+</Siri>`
+
+func mustParse(t *testing.T, body string) *Message {
+	t.Helper()
+	message, err := ParseMessage(body)
+	require.NoError(t, err)
+	return message
+}
+
+func Test_tracks_subscriptions_with_response_status(t *testing.T) {
+	// Given
+	subscriptions := NewSubscriptions()
+	response := `<Siri>
+	<SubscriptionResponse>
+		<ResponseTimestamp>2004-12-17T09:30:48Z</ResponseTimestamp>
+		<ResponderRef>server</ResponderRef>
+		<ResponseStatus>
+			<ResponseTimestamp>2004-12-17T09:30:48Z</ResponseTimestamp>
+			<SubscriptionRef>1</SubscriptionRef>
+			<Status>true</Status>
+		</ResponseStatus>
+		<ResponseStatus>
+			<ResponseTimestamp>2004-12-17T09:30:48Z</ResponseTimestamp>
+			<SubscriptionRef>2</SubscriptionRef>
+			<Status>false</Status>
+			<ErrorCondition>
+				<CapabilityNotSupportedError>
+					<ErrorText>VM not supported</ErrorText>
+				</CapabilityNotSupportedError>
+			</ErrorCondition>
+		</ResponseStatus>
+	</SubscriptionResponse>
+</Siri>`
+
+	// When
+	subscriptions.track("http://server/et", mustParse(t, etSubscriptionRequest), mustParse(t, response))
+
+	// Then
+	terminationTime := time.Date(2004, 12, 17, 11, 30, 47, 0, time.UTC)
+	actual := subscriptions.List()
+	require.Len(t, actual, 2)
+	assert.Equal(t, "1", actual[0].Identifier)
+	assert.Equal(t, "client", actual[0].SubscriberRef)
+	assert.Equal(t, "ET", actual[0].ServiceType)
+	assert.Equal(t, "http://server/et", actual[0].URL)
+	assert.True(t, terminationTime.Equal(actual[0].InitialTerminationTime))
+	assert.Equal(t, SubscriptionActive, actual[0].Status)
+	assert.Equal(t, "server", actual[0].ProducerRef)
+
+	assert.Equal(t, "2", actual[1].Identifier)
+	assert.Equal(t, "client", actual[1].SubscriberRef, "falls back to the RequestorRef")
+	assert.Equal(t, "VM", actual[1].ServiceType)
+	assert.Equal(t, SubscriptionRejected, actual[1].Status)
+	assert.Equal(t, "VM not supported", actual[1].ErrorText)
+
+	assert.Len(t, subscriptions.Changed, 1)
+}
+
+func Test_keeps_subscriptions_as_requested_without_subscription_response(t *testing.T) {
+	// Given
+	subscriptions := NewSubscriptions()
+
+	// When
+	subscriptions.track("http://server/et", mustParse(t, etSubscriptionRequest), nil)
+
+	// Then
+	for _, subscription := range subscriptions.List() {
+		assert.Equal(t, SubscriptionRequested, subscription.Status)
+	}
+}
+
+func Test_ignores_requests_which_are_no_subscription_requests(t *testing.T) {
+	// Given
+	subscriptions := NewSubscriptions()
+
+	// When
+	subscriptions.track("http://server/et", mustParse(t, "<Siri><DataSupplyRequest/></Siri>"), nil)
+	subscriptions.track("http://server/et", nil, nil)
+
+	// Then
+	assert.Empty(t, subscriptions.List())
+	assert.Empty(t, subscriptions.Changed)
+}
+
+func Test_terminates_subscriptions(t *testing.T) {
+	testCases := map[string]struct {
+		request          string
+		response         string
+		expectedStatuses []SubscriptionStatus
+	}{
+		"by subscription ref": {
+			request: `<Siri><TerminateSubscriptionRequest>
+				<RequestorRef>client</RequestorRef><SubscriptionRef>2</SubscriptionRef>
+			</TerminateSubscriptionRequest></Siri>`,
+			response: `<Siri><TerminateSubscriptionResponse><TerminationResponseStatus>
+				<SubscriberRef>client</SubscriberRef><SubscriptionRef>2</SubscriptionRef><Status>true</Status>
+			</TerminationResponseStatus></TerminateSubscriptionResponse></Siri>`,
+			expectedStatuses: []SubscriptionStatus{SubscriptionRequested, SubscriptionTerminated},
+		},
+		"all subscriptions": {
+			request: `<Siri><TerminateSubscriptionRequest>
+				<RequestorRef>client</RequestorRef><All/>
+			</TerminateSubscriptionRequest></Siri>`,
+			response: `<Siri><TerminateSubscriptionResponse><TerminationResponseStatus>
+				<SubscriptionRef>0000456</SubscriptionRef><Status>true</Status>
+			</TerminationResponseStatus></TerminateSubscriptionResponse></Siri>`,
+			expectedStatuses: []SubscriptionStatus{SubscriptionTerminated, SubscriptionTerminated},
+		},
+		"failed termination": {
+			request: `<Siri><TerminateSubscriptionRequest>
+				<RequestorRef>client</RequestorRef><All/>
+			</TerminateSubscriptionRequest></Siri>`,
+			response: `<Siri><TerminateSubscriptionResponse><TerminationResponseStatus>
+				<SubscriptionRef>1</SubscriptionRef><Status>false</Status>
+			</TerminationResponseStatus></TerminateSubscriptionResponse></Siri>`,
+			expectedStatuses: []SubscriptionStatus{SubscriptionRequested, SubscriptionRequested},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			subscriptions := NewSubscriptions()
+			subscriptions.track("http://server/et", mustParse(t, etSubscriptionRequest), nil)
+
+			// When
+			subscriptions.track("http://server/et", mustParse(t, tc.request), mustParse(t, tc.response))
+
+			// Then
+			var actualStatuses []SubscriptionStatus
+			for _, subscription := range subscriptions.List() {
+				actualStatuses = append(actualStatuses, subscription.Status)
+			}
+			assert.Equal(t, tc.expectedStatuses, actualStatuses)
+		})
+	}
+}
+
+func Test_resubscribing_replaces_the_subscription(t *testing.T) {
+	// Given
+	subscriptions := NewSubscriptions()
+	subscriptions.track("http://server/et", mustParse(t, etSubscriptionRequest), nil)
+
+	// When
+	subscriptions.track("http://server/et2", mustParse(t, etSubscriptionRequest), nil)
+
+	// Then
+	actual := subscriptions.List()
+	require.Len(t, actual, 2)
+	assert.Equal(t, "http://server/et2", actual[0].URL)
+}
+
+func Test_subscription_is_expired_after_initial_termination_time(t *testing.T) {
+	terminationTime := time.Date(2004, 12, 17, 11, 30, 47, 0, time.UTC)
+	subscription := Subscription{Status: SubscriptionActive, InitialTerminationTime: terminationTime}
+
+	assert.False(t, subscription.Expired(terminationTime.Add(-time.Minute)))
+	assert.True(t, subscription.Expired(terminationTime.Add(time.Minute)))
+}
@@ -3,12 +3,15 @@ package siri
 import (
 	"bytes"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"text/template"
 	"time"
+
+	"github.com/mszalbach/sirigo/internal/httputils"
 )
 
 // TemplateCache is used to execute file-system templates for SIRI communication
@@ -28,6 +31,13 @@ func NewTemplateCache(templatePath string) (TemplateCache, error) {
 // data is used to render the templates
 type data struct {
 	ClientRef string
+	// ConsumerAddress is the URL of the listener of the Client
+	ConsumerAddress string
+	// ProducerRef, ConsumerRef, SubscriberRef and SubscriptionRef are only set for deliveries of the Server
+	ProducerRef     string
+	ConsumerRef     string
+	SubscriberRef   string
+	SubscriptionRef string
 }
 
 var funcs = template.FuncMap{
@@ -53,8 +63,13 @@ func (tc TemplateCache) GetTemplate(name string) (string, error) {
 }
 
 type templateData struct {
-	Now       time.Time
-	ClientRef string
+	Now             time.Time
+	ClientRef       string
+	ConsumerAddress string
+	ProducerRef     string
+	ConsumerRef     string
+	SubscriberRef   string
+	SubscriptionRef string
 }
 
 // executeTemplate finds the template and executes it with the provided data
@@ -67,7 +82,15 @@ func executeTemplate(content string, data data) (string, error) {
 	var bytesBuffer bytes.Buffer
 	if err := siriTemplate.Execute(
 		&bytesBuffer,
-		templateData{Now: time.Now().UTC(), ClientRef: data.ClientRef},
+		templateData{
+			Now:             time.Now().UTC(),
+			ClientRef:       data.ClientRef,
+			ConsumerAddress: data.ConsumerAddress,
+			ProducerRef:     data.ProducerRef,
+			ConsumerRef:     data.ConsumerRef,
+			SubscriberRef:   data.SubscriberRef,
+			SubscriptionRef: data.SubscriptionRef,
+		},
 	); err != nil {
 		return "", err
 	}
@@ -103,6 +126,24 @@ func (tc TemplateCache) TemplateNames() ([]string, error) {
 	return templateNames, nil
 }
 
+var headerRegexp = regexp.MustCompile(`<!--\s*header:\s*(.*?)\s*-->`)
+
+// GetHeadersFromTemplate finds comments like <!-- header: X-Correlation-Id: 42 -->
+// used to specify additional headers of a SIRI client request. Invalid header comments are ignored.
+func GetHeadersFromTemplate(siriTemplate string) http.Header {
+	header := http.Header{}
+	for _, matches := range headerRegexp.FindAllStringSubmatch(siriTemplate, -1) {
+		parsed, err := httputils.ParseHeaders(matches[1])
+		if err != nil {
+			continue
+		}
+		for name, values := range parsed {
+			header[name] = append(header[name], values...)
+		}
+	}
+	return header
+}
+
 var urlPathRegexp = regexp.MustCompile(`<!--\s*path:\s*(.*?)\s*-->`)
 
 // GetURLPathFromTemplate finds a comment with an url path
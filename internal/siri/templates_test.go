@@ -1,6 +1,7 @@
 package siri
 
 import (
+	"net/http"
 	"os"
 	"testing"
 	"testing/synctest"
@@ -121,6 +122,30 @@ func Test_can_extract_url_paths_from_strings(t *testing.T) {
 	}
 }
 
+func Test_can_extract_headers_from_templates(t *testing.T) {
+	testCases := map[string]struct {
+		template string
+		expected http.Header
+	}{
+		"no header comment": {"<!-- path: /siri/et.xml --><Siri/>", http.Header{}},
+		"multiple headers": {
+			`<!-- path: /siri/et.xml -->
+<!-- header: Content-Type: text/xml; charset=ISO-8859-1 -->
+<!-- header: x-correlation-id: 42 -->
+<Siri/>`,
+			http.Header{"Content-Type": {"text/xml; charset=ISO-8859-1"}, "X-Correlation-Id": {"42"}},
+		},
+		"invalid header is ignored": {"<!-- header: no header --><Siri/>", http.Header{}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual := GetHeadersFromTemplate(tc.template)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
 func Test_returns_content_of_file_below_root_folder(t *testing.T) {
 	// Given
 	cache, err := NewTemplateCache("testdata")
@@ -0,0 +1,188 @@
+package siri
+
+import (
+	"encoding/xml"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Protocol is the protocol spoken with the server
+type Protocol string
+
+const (
+	// ProtocolSIRI uses SIRI messages and the URLs of the path comments in the templates
+	ProtocolSIRI Protocol = "siri"
+	// ProtocolVDV453 uses VDV453/VDV454 messages and their URL scheme
+	ProtocolVDV453 Protocol = "vdv453"
+)
+
+// VDV453 operations, they are the last part of the URL path
+const (
+	VDVSubscribe = "aboverwalten"
+	VDVDataReady = "datenbereit"
+	VDVFetchData = "datenabrufen"
+	// VDVStatus is status and not statusabfrage, VDV453 names the URL of the StatusAnfrage status.xml
+	VDVStatus       = "status"
+	VDVClientStatus = "clientstatus"
+)
+
+// vdvOperations maps the VDV453 requests to the operation of their URL
+var vdvOperations = map[string]string{
+	"AboAnfrage":          VDVSubscribe,
+	"DatenBereitAnfrage":  VDVDataReady,
+	"DatenAbrufenAnfrage": VDVFetchData,
+	"StatusAnfrage":       VDVStatus,
+	"ClientStatusAnfrage": VDVClientStatus,
+}
+
+// vdvMessageNames contains the root elements of all known VDV453 messages
+var vdvMessageNames = map[string]bool{
+	"AboAnfrage":          true,
+	"AboAntwort":          true,
+	"DatenBereitAnfrage":  true,
+	"DatenBereitAntwort":  true,
+	"DatenAbrufenAnfrage": true,
+	"DatenAbrufenAntwort": true,
+	"StatusAnfrage":       true,
+	"StatusAntwort":       true,
+	"ClientStatusAnfrage": true,
+	"ClientStatusAntwort": true,
+}
+
+// vdvResultOK is the Ergebnis of a successful request
+const vdvResultOK = "ok"
+
+// VDVMessage is a VDV453 message. The message type is defined by the root element like AboAnfrage.
+// Service specific content like the AboAUS elements is not parsed.
+type VDVMessage struct {
+	XMLName   xml.Name
+	Sender    string `xml:"Sender,attr,omitempty"`
+	Timestamp string `xml:"Zst,attr,omitempty"`
+	// Acknowledgement is the Bestaetigung of AboAntwort, DatenBereitAntwort and DatenAbrufenAntwort
+	Acknowledgement *VDVResult `xml:"Bestaetigung,omitempty"`
+	// Status is used by StatusAntwort and ClientStatusAntwort
+	Status           *VDVResult `xml:"Status,omitempty"`
+	DataReady        *bool      `xml:"DatenBereit,omitempty"`
+	AllData          *bool      `xml:"DatensatzAlle,omitempty"`
+	MoreData         *bool      `xml:"WeitereDaten,omitempty"`
+	ServiceStartTime string     `xml:"StartDienstZst,omitempty"`
+}
+
+// VDVResult is the result of a VDV453 request
+type VDVResult struct {
+	Timestamp   string `xml:"Zst,attr,omitempty"`
+	Result      string `xml:"Ergebnis,attr"`
+	ErrorNumber string `xml:"Fehlernummer,attr,omitempty"`
+	ErrorText   string `xml:"Fehlertext,omitempty"`
+}
+
+// VDVPath is the parsed path of a VDV453 URL like /leitstelle/ans/datenbereit.xml
+type VDVPath struct {
+	// Sender is the Leitstellenkennung of the sender of the request
+	Sender string
+	// Service is the Dienstkennung like ans, dfi or aus
+	Service   string
+	Operation string
+}
+
+// VDVURL builds the URL of a VDV453 operation: {serverURL}/{sender}/{service}/{operation}.xml
+func VDVURL(serverURL string, sender string, service string, operation string) string {
+	return strings.TrimSuffix(serverURL, "/") + "/" + sender + "/" + service + "/" + operation + ".xml"
+}
+
+// ParseVDVPath splits a URL path following the VDV453 scheme and reports whether this was possible
+func ParseVDVPath(urlPath string) (VDVPath, bool) {
+	parts := strings.Split(strings.Trim(urlPath, "/"), "/")
+	if len(parts) < 3 {
+		return VDVPath{}, false
+	}
+	operation, found := strings.CutSuffix(parts[len(parts)-1], ".xml")
+	if !found {
+		return VDVPath{}, false
+	}
+	// the server URL may contain a path prefix, so only the last parts are used
+	return VDVPath{Sender: parts[len(parts)-3], Service: parts[len(parts)-2], Operation: operation}, true
+}
+
+// ParseVDVMessage parses a VDV453 body and returns nil if it is not a known VDV453 message
+func ParseVDVMessage(body string) *VDVMessage {
+	if !vdvMessageNames[MessageElementName(body)] {
+		return nil
+	}
+	var message VDVMessage
+	if err := xml.Unmarshal([]byte(body), &message); err != nil {
+		return nil
+	}
+	return &message
+}
+
+// Marshal converts the message into a VDV453 XML body
+func (m VDVMessage) Marshal() (string, error) {
+	content, err := xml.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(content), nil
+}
+
+// Name returns the root element of the message like AboAntwort or an empty string for nil
+func (m *VDVMessage) Name() string {
+	if m == nil {
+		return ""
+	}
+	return m.XMLName.Local
+}
+
+// Result returns whether the Ergebnis of the Bestaetigung or Status is ok and reports if one was found
+func (m *VDVMessage) Result() (ok bool, found bool) {
+	switch {
+	case m == nil:
+		return false, false
+	case m.Acknowledgement != nil:
+		return m.Acknowledgement.Result == vdvResultOK, true
+	case m.Status != nil:
+		return m.Status.Result == vdvResultOK, true
+	}
+	return false, false
+}
+
+// vdvTimestamp formats the time like it is used in the Zst attributes
+func vdvTimestamp(now time.Time) string {
+	return now.Format(time.RFC3339)
+}
+
+// vdvAcknowledgement creates the answer of the client to requests of a VDV453 server.
+// Returns false for requests which are not answered by the client.
+func vdvAcknowledgement(request *VDVMessage, now time.Time, serviceStart time.Time) (VDVMessage, bool) {
+	switch request.Name() {
+	case "DatenBereitAnfrage":
+		return VDVMessage{
+			XMLName:         xml.Name{Local: "DatenBereitAntwort"},
+			Acknowledgement: &VDVResult{Timestamp: vdvTimestamp(now), Result: vdvResultOK, ErrorNumber: "0"},
+		}, true
+	case "ClientStatusAnfrage":
+		return VDVMessage{
+			XMLName:          xml.Name{Local: "ClientStatusAntwort"},
+			Status:           &VDVResult{Timestamp: vdvTimestamp(now), Result: vdvResultOK},
+			ServiceStartTime: vdvTimestamp(serviceStart),
+		}, true
+	}
+	return VDVMessage{}, false
+}
+
+// vdvTemplateURL derives the URL of a VDV453 template from its folder, which is used as Dienstkennung,
+// and the request within the template. Returns false if this is not possible.
+func vdvTemplateURL(serverURL string, sender string, name string, template string) (string, bool) {
+	folder := path.Dir(filepath.ToSlash(name))
+	if folder == "." {
+		return "", false
+	}
+	service := path.Base(folder)
+	operation, ok := vdvOperations[MessageElementName(template)]
+	if !ok {
+		return "", false
+	}
+	return VDVURL(serverURL, sender, service, operation), true
+}
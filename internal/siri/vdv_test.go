@@ -0,0 +1,179 @@
+package siri
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parse_vdv_path(t *testing.T) {
+	testCases := map[string]struct {
+		path     string
+		expected VDVPath
+		ok       bool
+	}{
+		"data ready":  {"/server/aus/datenbereit.xml", VDVPath{"server", "aus", VDVDataReady}, true},
+		"with prefix": {"/vdv/server/ans/clientstatus.xml", VDVPath{"server", "ans", VDVClientStatus}, true},
+		"too short":   {"/aus/datenbereit.xml", VDVPath{}, false},
+		"no xml":      {"/server/aus/datenbereit", VDVPath{}, false},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// When
+			actual, ok := ParseVDVPath(tc.path)
+
+			// Then
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func Test_template_url(t *testing.T) {
+	aboAnfrage := `<AboAnfrage Sender="{{ .ClientRef }}" Zst="{{ dateTime .Now }}"><AboAUS AboID="1"/></AboAnfrage>`
+	testCases := map[string]struct {
+		protocol Protocol
+		name     string
+		template string
+		expected string
+	}{
+		"path comment": {
+			ProtocolVDV453, "aus/aboAnfrage.xml", "<!-- path: /custom.xml -->" + aboAnfrage, "http://server/custom.xml",
+		},
+		"vdv scheme": {
+			ProtocolVDV453, "vdv453/aus/aboAnfrage.xml", aboAnfrage, "http://server/client/aus/aboverwalten.xml",
+		},
+		"no folder":         {ProtocolVDV453, "aboAnfrage.xml", aboAnfrage, "http://server"},
+		"no vdv request":    {ProtocolVDV453, "aus/test.xml", "<AboAntwort/>", "http://server"},
+		"siri protocol":     {ProtocolSIRI, "aus/aboAnfrage.xml", aboAnfrage, "http://server"},
+		"siri path comment": {ProtocolSIRI, "et/test.xml", "<!-- path: /siri/et --><Siri/>", "http://server/siri/et"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			client := NewClient("client", "http://server", "CLIENT ADDRESS", io.Discard)
+			client.Protocol = tc.protocol
+
+			// When
+			actual := client.TemplateURL(tc.name, tc.template)
+
+			// Then
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func Test_parse_vdv_message(t *testing.T) {
+	// When
+	ok := ParseVDVMessage(`<AboAntwort><Bestaetigung Ergebnis="ok" Fehlernummer="0"/></AboAntwort>`)
+	notOK := ParseVDVMessage(`<StatusAntwort><Status Ergebnis="notok"/><DatenBereit>true</DatenBereit></StatusAntwort>`)
+	siri := ParseVDVMessage(`<Siri><CheckStatusResponse/></Siri>`)
+
+	// Then
+	require.NotNil(t, ok)
+	assert.Equal(t, "AboAntwort", ok.Name())
+	result, found := ok.Result()
+	assert.True(t, result)
+	assert.True(t, found)
+
+	require.NotNil(t, notOK)
+	result, found = notOK.Result()
+	assert.False(t, result)
+	assert.True(t, found)
+	require.NotNil(t, notOK.DataReady)
+	assert.True(t, *notOK.DataReady)
+
+	assert.Nil(t, siri)
+	assert.Empty(t, siri.Name())
+}
+
+func Test_vdv_client_acknowledges_server_requests(t *testing.T) {
+	testCases := map[string]struct {
+		path     string
+		request  string
+		expected string
+	}{
+		"data ready": {
+			"/server/aus/datenbereit.xml",
+			`<DatenBereitAnfrage Sender="server" Zst="2024-01-01T10:00:00Z"/>`,
+			"DatenBereitAntwort",
+		},
+		"client status": {
+			"/server/aus/clientstatus.xml",
+			`<ClientStatusAnfrage Sender="server" Zst="2024-01-01T10:00:00Z"/>`,
+			"ClientStatusAntwort",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			client := NewClient("client", "http://server", "CLIENT ADDRESS", io.Discard)
+			client.Protocol = ProtocolVDV453
+			client.AutoClientResponse.Body = "<Siri/>"
+
+			// When
+			serverRequest, _ := http.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.request))
+			response := httptest.NewRecorder()
+			client.createHandler().ServeHTTP(response, serverRequest)
+
+			// Then
+			assert.Equal(t, http.StatusOK, response.Code)
+			message := ParseVDVMessage(response.Body.String())
+			require.NotNil(t, message)
+			assert.Equal(t, tc.expected, message.Name())
+			result, found := message.Result()
+			assert.True(t, result)
+			assert.True(t, found)
+			actualRequest := <-client.ServerRequest
+			assert.Equal(t, "server", actualRequest.VDVMessage.Sender)
+		})
+	}
+}
+
+func Test_vdv_client_fetches_data_until_no_more_data(t *testing.T) {
+	// Given
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/client/aus/datenabrufen.xml", req.URL.Path)
+		body, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "client", ParseVDVMessage(string(body)).Sender)
+		moreData := fetches.Add(1) == 1
+		rw.Header().Set("Content-Type", "application/xml")
+		fmt.Fprintf(rw, `<DatenAbrufenAntwort>
+	<Bestaetigung Ergebnis="ok" Fehlernummer="0"/>
+	<WeitereDaten>%t</WeitereDaten>
+</DatenAbrufenAntwort>`, moreData)
+	}))
+	defer server.Close()
+	client := NewClient("client", server.URL, "CLIENT ADDRESS", io.Discard)
+	client.Protocol = ProtocolVDV453
+	client.SetFetchedMode(true)
+
+	// When
+	serverRequest, _ := http.NewRequest(
+		http.MethodPost,
+		"/server/aus/datenbereit.xml",
+		strings.NewReader(`<DatenBereitAnfrage Sender="server"/>`),
+	)
+	client.createHandler().ServeHTTP(httptest.NewRecorder(), serverRequest)
+
+	// Then
+	for i := range 2 {
+		select {
+		case response := <-client.FetchedResponse:
+			assert.Equal(t, "DatenAbrufenAntwort", response.MessageName())
+		case <-time.After(5 * time.Second):
+			require.Failf(t, "missing fetched response", "response %d", i+1)
+		}
+	}
+	assert.Equal(t, int32(2), fetches.Load())
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/mszalbach/sirigo/internal/profile"
 	"github.com/mszalbach/sirigo/internal/siri"
 	"github.com/rivo/tview"
 )
@@ -16,6 +17,7 @@ type tuiApp interface {
 	QueueUpdateDraw(f func()) *tview.Application
 	register(prioritizedComponents ...tview.Primitive)
 	Suspend(func()) bool
+	SetTitle(title string) *tview.Application
 }
 
 // SiriApp is the main tview application for the SIRI client
@@ -29,19 +31,22 @@ func NewSiriApp(
 	siriClient *siri.Client,
 	sendTemplates siri.TemplateCache,
 	responseTemplates siri.TemplateCache,
+	pushTemplates siri.TemplateCache,
+	profiles []profile.Profile,
+	tlsOptions profile.TLSOptions,
 	cancel context.CancelCauseFunc,
 ) *SiriApp {
 	siriApp := &SiriApp{
 		Application:     tview.NewApplication(),
 		focusComponents: []tview.Primitive{},
 	}
-	siriApp.SetTitle(fmt.Sprintf("Sirigo (%s)", siriClient.ClientRef))
+	siriApp.SetTitle(appTitle(siriClient.ClientRef))
 
 	initStyles()
 	siriApp.EnableMouse(true)
 	siriApp.EnablePaste(true)
 
-	siriPage := newSiriPage(siriApp, siriClient, sendTemplates, responseTemplates)
+	siriPage := newSiriPage(siriApp, siriClient, sendTemplates, responseTemplates, pushTemplates, profiles, tlsOptions)
 	helpPage := newHelpPage()
 
 	pages := tview.NewPages()
@@ -63,6 +68,7 @@ func NewSiriApp(
 			siriPage.send()
 			return nil
 		case tcell.KeyCtrlC:
+			siriPage.cancel()
 			return nil
 		case tcell.KeyTab:
 			nextFocus(siriApp)
@@ -81,6 +87,10 @@ func NewSiriApp(
 	return siriApp
 }
 
+func appTitle(clientRef string) string {
+	return fmt.Sprintf("Sirigo (%s)", clientRef)
+}
+
 func (app *SiriApp) register(prioritizedComponents ...tview.Primitive) {
 	app.focusComponents = append(app.focusComponents, prioritizedComponents...)
 }
@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mszalbach/sirigo/internal/siri"
+	"github.com/rivo/tview"
+)
+
+var autoResponseRuleColumns = []string{"Path", "Element", "Selector", "Template", "Status"}
+
+type autoResponseRulesView struct {
+	*tview.Table
+	rules        *siri.AutoResponseRules
+	templates    siri.TemplateCache
+	errorChannel chan<- error
+}
+
+func newAutoResponseRulesView(
+	app tuiApp,
+	rules *siri.AutoResponseRules,
+	responseTemplates siri.TemplateCache,
+	errorChannel chan<- error,
+) autoResponseRulesView {
+	table := tview.NewTable().SetFixed(1, 0).SetSelectable(true, false)
+	table.SetBorder(true).SetTitle(fmt.Sprintf("Auto-response rules (%s)", siri.AutoResponseRulesFile))
+
+	view := autoResponseRulesView{
+		Table:        table,
+		rules:        rules,
+		templates:    responseTemplates,
+		errorChannel: errorChannel,
+	}
+	view.reload()
+
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyCtrlE:
+			app.Suspend(view.edit)
+			return nil
+		case event.Rune() == 'r':
+			view.reload()
+			return nil
+		}
+		return event
+	})
+
+	// register focus order
+	app.register(table)
+
+	return view
+}
+
+// reload reads the rules file from the autoresponse folder
+func (rv autoResponseRulesView) reload() {
+	rules, err := siri.LoadAutoResponseRules(rv.templates)
+	if err != nil {
+		rv.errorChannel <- fmt.Errorf("could not load auto-response rules: %w", err)
+		return
+	}
+	rv.rules.SetRules(rules)
+	rv.update()
+}
+
+// edit opens the rules as JSON in the editor and stores them in the rules file afterwards
+func (rv autoResponseRulesView) edit() {
+	content, err := json.MarshalIndent(rv.rules.Rules(), "", "  ")
+	if err != nil {
+		rv.errorChannel <- err
+		return
+	}
+	edited, err := editInEditor("rules", string(content))
+	if err != nil {
+		rv.errorChannel <- err
+		return
+	}
+
+	var rules []siri.AutoResponseRule
+	if err := json.Unmarshal([]byte(edited), &rules); err != nil {
+		rv.errorChannel <- fmt.Errorf("invalid auto-response rules: %w", err)
+		return
+	}
+	for i := range rules {
+		if err := rules[i].LoadTemplate(rv.templates); err != nil {
+			rv.errorChannel <- fmt.Errorf("invalid auto-response rule %d: %w", i+1, err)
+			return
+		}
+	}
+	if err := siri.SaveAutoResponseRules(rv.templates, rules); err != nil {
+		rv.errorChannel <- fmt.Errorf("could not save auto-response rules: %w", err)
+	}
+	rv.rules.SetRules(rules)
+	rv.update()
+}
+
+func (rv autoResponseRulesView) update() {
+	rv.Clear()
+	for column, title := range autoResponseRuleColumns {
+		rv.SetCell(0, column, tview.NewTableCell(title).SetSelectable(false).SetTextColor(colors["purple"]))
+	}
+	for i, rule := range rv.rules.Rules() {
+		row := i + 1
+		rv.SetCellSimple(row, 0, rule.Path)
+		rv.SetCellSimple(row, 1, rule.Element)
+		rv.SetCellSimple(row, 2, rule.Selector)
+		rv.SetCellSimple(row, 3, rule.Template)
+		rv.SetCellSimple(row, 4, strconv.Itoa(rule.Status))
+	}
+}
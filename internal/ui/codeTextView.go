@@ -2,6 +2,7 @@ package ui
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
@@ -43,16 +44,26 @@ func (ctv *codeTextView) SetCode(code string, language string) {
 }
 
 func (ctv *codeTextView) openInEditor() {
-	f, err := os.CreateTemp("", ctv.GetTitle()+"-*.txt")
+	if _, err := editInEditor(ctv.GetTitle(), ctv.GetText(true)); err != nil {
+		slog.Warn("Could not open content in editor", slog.Any("error", err))
+	}
+}
+
+// editInEditor opens the content in the editor defined by the EDITOR environment variable
+// and returns the content after the editor was closed
+func editInEditor(name string, content string) (string, error) {
+	f, err := os.CreateTemp("", name+"-*.txt")
 	if err != nil {
-		return
+		return "", err
 	}
 	defer os.Remove(f.Name())
 
-	_, err = f.WriteString(ctv.GetText(true))
+	_, err = f.WriteString(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		slog.Warn("Could not write to tmp file", slog.String("file", f.Name()), slog.Any("error", err))
-		return
+		return "", fmt.Errorf("could not write to tmp file %s: %w", f.Name(), err)
 	}
 
 	editor := os.Getenv("EDITOR")
@@ -73,11 +84,12 @@ func (ctv *codeTextView) openInEditor() {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
-		slog.Warn(
-			"Could not start editor",
-			slog.String("editor", editor),
-			slog.String("file", f.Name()),
-			slog.Any("error", err),
-		)
+		return "", fmt.Errorf("could not start editor %s for %s: %w", editor, f.Name(), err)
+	}
+
+	edited, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
 	}
+	return string(edited), nil
 }
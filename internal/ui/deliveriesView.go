@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/mszalbach/sirigo/internal/siri"
+	"github.com/rivo/tview"
+)
+
+var deliveryColumns = []string{"Time", "Producer", "Service", "Subscriber", "Subscription", "Status"}
+
+type deliveriesView struct {
+	*listTable[siri.DirectDelivery]
+	deliveries *siri.Deliveries
+}
+
+// newDeliveriesView shows the ServiceDeliveries received in direct delivery mode.
+// Enter calls show for the selected delivery.
+func newDeliveriesView(
+	app tuiApp,
+	deliveries *siri.Deliveries,
+	show func(siri.DirectDelivery),
+) *deliveriesView {
+	table := newListTable("Deliveries", deliveryColumns, deliveryTime, deliveryCells)
+	view := &deliveriesView{listTable: table, deliveries: deliveries}
+	view.update()
+
+	go func() {
+		for range deliveries.Changed {
+			app.QueueUpdateDraw(view.update)
+		}
+	}()
+
+	table.SetSelectedFunc(func(row int, _ int) {
+		if delivery, ok := table.entry(row); ok {
+			show(delivery)
+		}
+	})
+
+	// register focus order
+	app.register(table)
+
+	return view
+}
+
+func (dv *deliveriesView) update() {
+	dv.setEntries(dv.deliveries.List())
+}
+
+func deliveryTime(delivery siri.DirectDelivery) time.Time {
+	return delivery.ReceivedAt
+}
+
+func deliveryCells(delivery siri.DirectDelivery) []*tview.TableCell {
+	status := tview.NewTableCell("subscribed").SetTextColor(colors["green"])
+	if !delivery.Subscribed {
+		status = tview.NewTableCell("unknown subscription").SetTextColor(colors["red"])
+	}
+	return []*tview.TableCell{
+		tview.NewTableCell(delivery.ReceivedAt.Local().Format(time.TimeOnly)),
+		tview.NewTableCell(delivery.ProducerRef),
+		tview.NewTableCell(delivery.ServiceType),
+		tview.NewTableCell(delivery.SubscriberRef),
+		tview.NewTableCell(delivery.SubscriptionRef),
+		status,
+	}
+}
@@ -21,6 +21,10 @@ func newHelpPage() *helpPage {
 
 Some things can be configured when starting Sirigo.
 Use -h or --help to see all available command line options.
+When started with -schemas, violations of sent and received bodies against the XSD files are shown in the status bar.
+When started with -profiles, the Profile dropdown switches between the servers of the profiles file.
+When started with -protocol vdv453, requests use the VDV453 URL scheme and server requests are acknowledged automatically.
+When started with -pushtargets, the Target dropdown switches between the server and consumers to push producer messages to.
 
 Global Keybindings:
 
@@ -30,8 +34,13 @@ Ctrl-X: Exit the application
 SIRI page Keybindings:
 
 Ctrl-O: 	   Send a SIRI request
+Ctrl-C: 	   Cancel the request which is currently sent
 Tab/Shift+Tab: Cycle focus between components
 
+Headers:
+
+One header per line like Content-Type: text/xml. They are filled from the <!-- header: --> comments of a template.
+
 Client Request:
 
 Ctrl-D:		   Delete the character under the cursor (or the first character on the next line if the cursor is at the end of a line).
@@ -40,6 +49,15 @@ Ctrl-K:        Delete everything under and to the right of the cursor until the
 Ctrl-W:        Delete from the start of the current word to the left of the cursor.
 Ctrl-U:        Delete the current line, i.e. everything after the last newline character before the cursor up until the next newline character. This may span multiple visible rows if wrapping is enabled.
 
+Server:
+
+Default auto-response: The response sent for incoming server requests when no auto-response rule matches.
+Auto-response rules:   The first rule matching the URL path, message element and selector of a request is used.
+                       Ctrl-E: Edit the rules in the editor, they are stored in the rules.json of the autoresponse folder.
+                       r:      Reload the rules from the rules.json.
+Fetched mode: When checked, a DataSupplyRequest is sent automatically after a DataReadyNotification was acknowledged.
+              The ServiceDelivery is shown in the Server Response.
+
 Server Response / Server Request:
 
 h: 		Move left.
@@ -51,6 +69,25 @@ G: 		Move to the bottom.
 Ctrl-F: Move down by one page.
 Ctrl-B: Move up by one page.
 Ctrl-E: Open the current content in the editor defined by the EDITOR environment variable. If not set, vi/notepad is used.
+
+Subscriptions:
+
+Lists all subscriptions sent with a SubscriptionRequest and their status reported by the server.
+Terminated subscriptions are updated when a TerminateSubscriptionResponse arrives.
+Deliveries counts the ServiceDeliveries the server sent directly for the subscription.
+If a subscription requests a HeartbeatInterval, the status bar shows an alarm when a HeartbeatNotification is overdue.
+
+Deliveries:
+
+Lists the ServiceDeliveries the server sent in direct delivery mode per service, the newest first.
+They are acknowledged with a DataReceivedAcknowledgement which fails for unknown subscriptions, unless an auto-response rule matches.
+Enter: Show the ServiceDelivery in the Server Request.
+
+History:
+
+Lists all requests sent by the client (→) and received from the server (←) with their responses, the newest first.
+Enter: Show the selected request again. Requests sent by the client are loaded into the Client Request to change and send them.
+r:     Replay the selected request exactly as it was sent before, without executing the template again.
 `)
 	helpPage.AddItem(textview, 0, 1, true)
 	return helpPage
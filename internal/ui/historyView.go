@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"cmp"
+	"strconv"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mszalbach/sirigo/internal/siri"
+	"github.com/rivo/tview"
+)
+
+var historyColumns = []string{"Time", "", "Message", "Status", "URL"}
+
+type historyView struct {
+	*listTable[siri.Exchange]
+	history *siri.History
+}
+
+// newHistoryView shows all exchanges of the client. Enter calls show and r calls replay for the selected exchange.
+func newHistoryView(
+	app tuiApp,
+	history *siri.History,
+	show func(siri.Exchange),
+	replay func(siri.Exchange),
+) *historyView {
+	table := newListTable("History", historyColumns, exchangeTime, exchangeCells)
+	view := &historyView{listTable: table, history: history}
+	view.update()
+
+	go func() {
+		for range history.Changed {
+			app.QueueUpdateDraw(view.update)
+		}
+	}()
+
+	table.SetSelectedFunc(func(row int, _ int) {
+		if exchange, ok := table.entry(row); ok {
+			show(exchange)
+		}
+	})
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Rune() == 'r' {
+			row, _ := table.GetSelection()
+			if exchange, ok := table.entry(row); ok {
+				replay(exchange)
+			}
+			return nil
+		}
+		return event
+	})
+
+	// register focus order
+	app.register(table)
+
+	return view
+}
+
+func (hv *historyView) update() {
+	hv.setEntries(hv.history.List())
+}
+
+func exchangeTime(exchange siri.Exchange) time.Time {
+	return exchange.Time
+}
+
+func exchangeCells(exchange siri.Exchange) []*tview.TableCell {
+	direction := "→"
+	if exchange.Direction == siri.Incoming {
+		direction = "←"
+	}
+	return []*tview.TableCell{
+		tview.NewTableCell(exchange.Time.Local().Format(time.TimeOnly)),
+		tview.NewTableCell(direction),
+		tview.NewTableCell(cmp.Or(exchange.Name(), "-")),
+		tview.NewTableCell(exchangeStatus(exchange)).SetTextColor(exchangeStatusColor(exchange)),
+		tview.NewTableCell(exchange.URL),
+	}
+}
+
+func exchangeStatus(exchange siri.Exchange) string {
+	if exchange.Error != "" {
+		return "error"
+	}
+	return strconv.Itoa(exchange.Status)
+}
+
+func exchangeStatusColor(exchange siri.Exchange) tcell.Color {
+	switch {
+	case exchange.Error != "" || exchange.Status >= 400:
+		return colors["red"]
+	case exchange.Status >= 200 && exchange.Status < 300:
+		return colors["green"]
+	}
+	return colors["orange"]
+}
@@ -18,6 +18,10 @@ var keys = []struct {
 		key:         "Ctrl-O",
 		description: "Send",
 	},
+	{
+		key:         "Ctrl-C",
+		description: "Cancel",
+	},
 	{
 		key:         "Ctrl-E",
 		description: "Editor",
@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// listTable shows entries like exchanges or deliveries in a table, the newest first,
+// so new entries are visible without scrolling
+type listTable[T any] struct {
+	*tview.Table
+	columns []string
+	// timeOf and cells describe an entry, the time identifies the entry across updates
+	timeOf func(T) time.Time
+	cells  func(T) []*tview.TableCell
+	// entries are the displayed entries, the newest first
+	entries []T
+	keys    []entryKey
+}
+
+// entryKey identifies an entry by its time and its position among the entries with the same time
+type entryKey struct {
+	time       int64
+	occurrence int
+}
+
+func newListTable[T any](
+	title string,
+	columns []string,
+	timeOf func(T) time.Time,
+	cells func(T) []*tview.TableCell,
+) *listTable[T] {
+	table := tview.NewTable().SetFixed(1, 0).SetSelectable(true, false)
+	table.SetBorder(true).SetTitle(title)
+	return &listTable[T]{Table: table, columns: columns, timeOf: timeOf, cells: cells}
+}
+
+// entry returns the entry shown in the row
+func (lt *listTable[T]) entry(row int) (T, bool) {
+	index := row - 1
+	if index < 0 || index >= len(lt.entries) {
+		var none T
+		return none, false
+	}
+	return lt.entries[index], true
+}
+
+// setEntries shows the entries which are ordered the oldest first.
+// The selected entry stays selected even if entries were added or dropped.
+func (lt *listTable[T]) setEntries(entries []T) {
+	selectedRow, selectedColumn := lt.GetSelection()
+	selectedIndex := selectedRow - 1
+	var selected entryKey
+	hasSelection := selectedIndex >= 0 && selectedIndex < len(lt.keys)
+	if hasSelection {
+		selected = lt.keys[selectedIndex]
+	}
+
+	occurrences := map[int64]int{}
+	keys := make([]entryKey, len(entries))
+	for i, entry := range entries {
+		nanos := lt.timeOf(entry).UnixNano()
+		keys[i] = entryKey{time: nanos, occurrence: occurrences[nanos]}
+		occurrences[nanos]++
+	}
+	lt.entries = make([]T, 0, len(entries))
+	lt.keys = make([]entryKey, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		lt.entries = append(lt.entries, entries[i])
+		lt.keys = append(lt.keys, keys[i])
+	}
+
+	lt.Clear()
+	for column, title := range lt.columns {
+		lt.SetCell(0, column, tview.NewTableCell(title).SetSelectable(false).SetTextColor(colors["purple"]))
+	}
+	for i, entry := range lt.entries {
+		for column, cell := range lt.cells(entry) {
+			lt.SetCell(i+1, column, cell)
+		}
+	}
+
+	if !hasSelection {
+		return
+	}
+	for i, key := range lt.keys {
+		if key == selected {
+			lt.Select(i+1, selectedColumn)
+			return
+		}
+	}
+	// the selected entry was dropped, the oldest entry is the closest one
+	if len(lt.entries) > 0 {
+		lt.Select(len(lt.entries), selectedColumn)
+	}
+}
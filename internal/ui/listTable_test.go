@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type listEntry struct {
+	name string
+	at   time.Time
+}
+
+func newTestListTable() *listTable[listEntry] {
+	return newListTable(
+		"Entries",
+		[]string{"Name"},
+		func(entry listEntry) time.Time { return entry.at },
+		func(entry listEntry) []*tview.TableCell { return []*tview.TableCell{tview.NewTableCell(entry.name)} },
+	)
+}
+
+// listEntries creates entries with the names from to to, the oldest first
+func listEntries(from int, to int) []listEntry {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	var entries []listEntry
+	for i := from; i <= to; i++ {
+		entries = append(entries, listEntry{name: strconv.Itoa(i), at: start.Add(time.Duration(i) * time.Second)})
+	}
+	return entries
+}
+
+func Test_list_table_keeps_selected_entry(t *testing.T) {
+	testCases := map[string]struct {
+		updated  []listEntry
+		expected string
+	}{
+		"entries added":              {listEntries(1, 5), "2"},
+		"limit reached":              {listEntries(2, 4), "2"},
+		"same time as other entries": {append(listEntries(1, 3), listEntry{name: "same", at: listEntries(3, 3)[0].at}), "2"},
+		"selected entry dropped":     {listEntries(3, 5), "3"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Given
+			table := newTestListTable()
+			table.setEntries(listEntries(1, 3))
+			table.Select(2, 0)
+
+			// When
+			table.setEntries(tc.updated)
+
+			// Then
+			row, _ := table.GetSelection()
+			selected, ok := table.entry(row)
+			require.True(t, ok)
+			assert.Equal(t, tc.expected, selected.name)
+		})
+	}
+}
+
+func Test_list_table_shows_newest_entry_first(t *testing.T) {
+	// Given
+	table := newTestListTable()
+
+	// When
+	table.setEntries(listEntries(1, 3))
+
+	// Then
+	assert.Equal(t, "Name", table.GetCell(0, 0).Text)
+	assert.Equal(t, "3", table.GetCell(1, 0).Text)
+	assert.Equal(t, "1", table.GetCell(3, 0).Text)
+	_, ok := table.entry(0)
+	assert.False(t, ok, "the header is no entry")
+}
@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mszalbach/sirigo/internal/httputils"
+	"github.com/mszalbach/sirigo/internal/siri"
+	"github.com/rivo/tview"
+)
+
+// metadataHeight is the height of a metadataView within a layout, more headers can be scrolled
+const metadataHeight = 4
+
+// metadataView shows the status, timing, size and headers of an HTTP message
+type metadataView struct {
+	*tview.TextView
+}
+
+func newMetadataView() metadataView {
+	textView := tview.NewTextView().SetDynamicColors(true)
+	return metadataView{textView}
+}
+
+func (mv metadataView) setResponse(response siri.ServerResponse) {
+	mv.SetText(formatMetadata(responseSummary(response), response.Header))
+	mv.ScrollToBeginning()
+}
+
+func (mv metadataView) setRequest(request siri.ServerRequest) {
+	mv.SetText(formatMetadata(requestSummary(request), request.Header))
+	mv.ScrollToBeginning()
+}
+
+// responseSummary returns status, duration, size and receive time, it is empty if no response was received
+func responseSummary(response siri.ServerResponse) []string {
+	if response.Status == 0 {
+		return nil
+	}
+	return []string{
+		fmt.Sprintf("%d %s", response.Status, http.StatusText(response.Status)),
+		formatDuration(response.Duration),
+		formatSize(response.Size),
+		response.ReceivedAt.Local().Format(time.TimeOnly),
+	}
+}
+
+// requestSummary returns method, size and receive time
+func requestSummary(request siri.ServerRequest) []string {
+	if request.ReceivedAt.IsZero() {
+		return nil
+	}
+	return []string{
+		request.Method,
+		formatSize(request.Size),
+		request.ReceivedAt.Local().Format(time.TimeOnly),
+	}
+}
+
+// formatMetadata writes the summary in the first line followed by the headers
+func formatMetadata(summary []string, header http.Header) string {
+	var builder strings.Builder
+	if len(summary) > 0 {
+		builder.WriteString(keyColor + strings.Join(summary, "  ") + descriptionColor + "\n")
+	}
+	builder.WriteString(tview.Escape(httputils.FormatHeaders(header)))
+	return builder.String()
+}
+
+func formatDuration(duration time.Duration) string {
+	if duration < time.Millisecond {
+		return duration.Round(time.Microsecond).String()
+	}
+	return duration.Round(time.Millisecond).String()
+}
+
+func formatSize(size int) string {
+	switch {
+	case size < 1000:
+		return fmt.Sprintf("%d B", size)
+	case size < 1000*1000:
+		return fmt.Sprintf("%.1f kB", float64(size)/1000)
+	}
+	return fmt.Sprintf("%.1f MB", float64(size)/(1000*1000))
+}
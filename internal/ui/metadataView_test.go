@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mszalbach/sirigo/internal/siri"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_shows_response_metadata(t *testing.T) {
+	// Given
+	screen := newTestScreen(t)
+	defer screen.Fini()
+	view := newMetadataView()
+	view.SetRect(0, 0, 60, metadataHeight)
+	receivedAt := time.Date(2024, 1, 1, 10, 15, 2, 0, time.Local)
+
+	// When
+	view.setResponse(siri.ServerResponse{
+		Status:     http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/xml"}, "X-Request-Id": {"[42]"}},
+		Duration:   125*time.Millisecond + 300*time.Microsecond,
+		Size:       1234,
+		ReceivedAt: receivedAt,
+	})
+	view.Draw(screen)
+
+	// Then
+	assert.Equal(t, "200 OK  125ms  1.2 kB  10:15:02", getScreenTextLine(screen, 0, 31))
+	assert.Equal(t, "Content-Type: application/xml", getScreenTextLine(screen, 1, 29))
+	assert.Equal(t, "X-Request-Id: [42]", getScreenTextLine(screen, 2, 18))
+}
+
+func Test_shows_only_headers_without_response(t *testing.T) {
+	// Given
+	screen := newTestScreen(t)
+	defer screen.Fini()
+	view := newMetadataView()
+	view.SetRect(0, 0, 60, metadataHeight)
+
+	// When
+	view.setResponse(siri.ServerResponse{Body: "connection refused"})
+	view.Draw(screen)
+
+	// Then
+	assert.Empty(t, getScreenTextLine(screen, 0, 10))
+}
+
+func Test_format_size(t *testing.T) {
+	testCases := map[int]string{
+		0:         "0 B",
+		999:       "999 B",
+		1000:      "1.0 kB",
+		2_500_000: "2.5 MB",
+	}
+	for size, expected := range testCases {
+		assert.Equal(t, expected, formatSize(size))
+	}
+}
+
+func Test_format_duration(t *testing.T) {
+	assert.Equal(t, "350µs", formatDuration(350*time.Microsecond+200))
+	assert.Equal(t, "1.235s", formatDuration(1234567*time.Microsecond))
+}
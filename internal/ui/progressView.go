@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// progressInterval is the time between two updates of the spinner and the elapsed time
+const progressInterval = 100 * time.Millisecond
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// progressView shows a spinner and the elapsed time while a request is pending
+type progressView struct {
+	*tview.TextView
+	app tuiApp
+}
+
+func newProgressView(app tuiApp) progressView {
+	textView := tview.NewTextView().SetDynamicColors(true)
+	return progressView{TextView: textView, app: app}
+}
+
+// start shows the progress until the returned function is called
+func (pv progressView) start() func() {
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for frame := 0; ; frame++ {
+			text := progressText(frame, time.Since(start))
+			pv.app.QueueUpdateDraw(func() {
+				pv.SetText(text)
+			})
+			select {
+			case <-done:
+				pv.app.QueueUpdateDraw(func() {
+					pv.SetText("")
+				})
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+func progressText(frame int, elapsed time.Duration) string {
+	return fmt.Sprintf(
+		"%s Sending %s %sCtrl-C%s to cancel",
+		spinnerFrames[frame%len(spinnerFrames)],
+		elapsed.Round(progressInterval),
+		keyColor,
+		descriptionColor,
+	)
+}
@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_progress_text_shows_spinner_and_elapsed_time(t *testing.T) {
+	// When
+	first := progressText(0, 1234*time.Millisecond)
+	next := progressText(len(spinnerFrames)+1, 0)
+
+	// Then
+	assert.Equal(t, "⠋ Sending 1.2s "+keyColor+"Ctrl-C"+descriptionColor+" to cancel", first)
+	assert.Contains(t, next, "⠙ Sending 0s")
+}
+
+// queuedAppMock hands the queued updates to the test, so they run on the test goroutine like in the tview event loop
+type queuedAppMock struct {
+	AppMock
+	updates chan func()
+}
+
+func (app *queuedAppMock) QueueUpdateDraw(f func()) *tview.Application {
+	app.updates <- f
+	return nil
+}
+
+// runUpdates runs the queued updates until the condition is met
+func (app *queuedAppMock) runUpdates(t *testing.T, condition func() bool) {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for !condition() {
+		select {
+		case update := <-app.updates:
+			update()
+		case <-timeout:
+			require.Fail(t, "condition not met by the queued updates")
+		}
+	}
+}
+
+func Test_progress_is_cleared_when_stopped(t *testing.T) {
+	// Given
+	queuedApp := &queuedAppMock{updates: make(chan func())}
+	view := newProgressView(queuedApp)
+
+	// When
+	stop := view.start()
+	queuedApp.runUpdates(t, func() bool {
+		return view.GetText(true) != ""
+	})
+	stop()
+
+	// Then
+	queuedApp.runUpdates(t, func() bool {
+		return view.GetText(true) == ""
+	})
+}
@@ -1,8 +1,12 @@
 package ui
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
+	"github.com/mszalbach/sirigo/internal/httputils"
+	"github.com/mszalbach/sirigo/internal/profile"
 	"github.com/mszalbach/sirigo/internal/siri"
 	"github.com/rivo/tview"
 )
@@ -12,13 +16,20 @@ type siriClientView struct {
 	siriClient   *siri.Client
 	errorChannel chan<- error
 	urlInput     *tview.InputField
+	headersArea  *tview.TextArea
 	requestArea  *tview.TextArea
+	dropdown     *tview.DropDown
+	templates    *siri.TemplateCache
+	// targetDropdown selects the server or one of the push targets, it is nil without push targets
+	targetDropdown *tview.DropDown
+	pushTemplates  siri.TemplateCache
 }
 
 func newSiriClientView(
 	app tuiApp,
 	siriClient *siri.Client,
 	sendTemplates siri.TemplateCache,
+	pushTemplates siri.TemplateCache,
 	errorChannel chan<- error,
 ) siriClientView {
 	urlInput := tview.NewInputField().SetPlaceholder("http://localhost:8080")
@@ -27,50 +38,184 @@ func newSiriClientView(
 	urlInput.SetText(siriClient.ServerURL)
 
 	siriClientRequestArea := tview.NewTextArea()
-	siriClientRequestArea.SetBorder(true).SetTitle(fmt.Sprintf("Client Request (clientRef: %s)", siriClient.ClientRef))
+	siriClientRequestArea.SetBorder(true).SetTitle(requestTitle(siriClient.ClientRef))
+
+	headersArea := tview.NewTextArea().SetPlaceholder("Name: value")
+	headersArea.SetBorder(true).SetTitle("Headers")
 
 	dropdown := tview.NewDropDown().SetLabel("Templates: ")
 
-	templateNames, err := sendTemplates.TemplateNames()
-	if err == nil {
-		dropdown.SetOptions(templateNames, nil)
-	} else {
-		errorChannel <- err
+	siriClientView := siriClientView{
+		siriClient:    siriClient,
+		errorChannel:  errorChannel,
+		urlInput:      urlInput,
+		headersArea:   headersArea,
+		requestArea:   siriClientRequestArea,
+		dropdown:      dropdown,
+		templates:     &sendTemplates,
+		pushTemplates: pushTemplates,
 	}
-
 	dropdown.SetSelectedFunc(func(name string, _ int) {
-		requestTemplate, err := sendTemplates.GetTemplate(name)
-		if err != nil {
-			errorChannel <- err
-			return
-		}
-		urlPath := siri.GetURLPathFromTemplate(requestTemplate)
-		urlInput.SetText(siriClient.ServerURL + urlPath)
-		siriClientRequestArea.SetText(requestTemplate, false)
+		siriClientView.selectTemplate(name)
 	})
 
-	// register focus order
-	app.register(urlInput, dropdown, siriClientRequestArea)
+	templatesFlex := tview.NewFlex()
+	if len(siriClient.PushTargets) > 0 {
+		targetNames := []string{"server"}
+		for _, target := range siriClient.PushTargets {
+			targetNames = append(targetNames, target.Name)
+		}
+		targetDropdown := tview.NewDropDown().SetLabel("Target: ").SetOptions(targetNames, nil)
+		targetDropdown.SetCurrentOption(0)
+		targetDropdown.SetSelectedFunc(func(_ string, _ int) {
+			siriClientView.selectTarget()
+		})
+		siriClientView.targetDropdown = targetDropdown
+		templatesFlex.AddItem(targetDropdown, 0, 1, false)
 
-	flex := tview.NewFlex().
+		// register focus order
+		app.register(urlInput, targetDropdown, dropdown, headersArea, siriClientRequestArea)
+	} else {
+		// register focus order
+		app.register(urlInput, dropdown, headersArea, siriClientRequestArea)
+	}
+	templatesFlex.AddItem(dropdown, 0, 2, false)
+
+	siriClientView.Flex = tview.NewFlex().
 		SetDirection(tview.FlexRow).
 		AddItem(urlInput, 2, 0, false).
-		AddItem(dropdown, 2, 0, false).
+		AddItem(templatesFlex, 2, 0, false).
+		AddItem(headersArea, 5, 0, false).
 		AddItem(siriClientRequestArea, 0, 1, false)
 
-	return siriClientView{
-		Flex:         flex,
-		siriClient:   siriClient,
-		errorChannel: errorChannel,
-		urlInput:     urlInput,
-		requestArea:  siriClientRequestArea,
+	siriClientView.updateTemplateNames()
+	return siriClientView
+}
+
+func requestTitle(clientRef string) string {
+	return fmt.Sprintf("Client Request (clientRef: %s)", clientRef)
+}
+
+// pushTarget returns the selected push target, false if the requests are sent to the server
+func (sc siriClientView) pushTarget() (siri.PushTarget, bool) {
+	if sc.targetDropdown == nil {
+		return siri.PushTarget{}, false
+	}
+	index, _ := sc.targetDropdown.GetCurrentOption()
+	if index <= 0 {
+		return siri.PushTarget{}, false
+	}
+	return sc.siriClient.PushTargets[index-1], true
+}
+
+// activeTemplates are the push templates if a push target is selected and the send templates otherwise
+func (sc siriClientView) activeTemplates() siri.TemplateCache {
+	if _, ok := sc.pushTarget(); ok {
+		return sc.pushTemplates
+	}
+	return *sc.templates
+}
+
+func (sc siriClientView) selectTemplate(name string) {
+	requestTemplate, err := sc.activeTemplates().GetTemplate(name)
+	if err != nil {
+		sc.errorChannel <- err
+		return
+	}
+	if target, ok := sc.pushTarget(); ok {
+		sc.urlInput.SetText(target.TemplateURL(requestTemplate))
+	} else {
+		sc.urlInput.SetText(sc.siriClient.TemplateURL(name, requestTemplate))
+	}
+	sc.headersArea.SetText(httputils.FormatHeaders(siri.GetHeadersFromTemplate(requestTemplate)), false)
+	sc.requestArea.SetText(requestTemplate, false)
+}
+
+// selectTarget switches the URL and the templates between the server and a push target
+func (sc siriClientView) selectTarget() {
+	if target, ok := sc.pushTarget(); ok {
+		sc.urlInput.SetText(target.URL)
+	} else {
+		sc.urlInput.SetText(sc.siriClient.ServerURL)
+	}
+	sc.updateTemplateNames()
+}
+
+func (sc siriClientView) updateTemplateNames() {
+	templateNames, err := sc.activeTemplates().TemplateNames()
+	if err != nil {
+		sc.errorChannel <- err
+		return
+	}
+	sc.dropdown.SetOptions(templateNames, nil)
+}
+
+// useProfile shows the server and templates of the profile the client was switched to
+func (sc siriClientView) useProfile(p profile.Profile) {
+	if _, ok := sc.pushTarget(); !ok {
+		sc.urlInput.SetText(sc.siriClient.ServerURL)
+	}
+	sc.requestArea.SetTitle(requestTitle(sc.siriClient.ClientRef))
+	if p.Templates == "" {
+		return
+	}
+	templates, err := siri.NewTemplateCache(p.Templates)
+	if err != nil {
+		sc.errorChannel <- err
+		return
 	}
+	*sc.templates = templates
+	sc.updateTemplateNames()
 }
 
-func (sc siriClientView) send() siri.ServerResponse {
-	res, err := sc.siriClient.Send(siri.ClientRequest{URL: sc.urlInput.GetText(), Body: sc.requestArea.GetText()})
+func (sc siriClientView) send(ctx context.Context) siri.ServerResponse {
+	header, err := httputils.ParseHeaders(sc.headersArea.GetText())
+	if err != nil {
+		sc.errorChannel <- err
+		return siri.ServerResponse{}
+	}
+	request := siri.ClientRequest{
+		URL:    sc.urlInput.GetText(),
+		Body:   sc.requestArea.GetText(),
+		Header: header,
+	}
+	send := sc.siriClient.Send
+	if _, ok := sc.pushTarget(); ok {
+		send = sc.siriClient.Push
+	}
+	res, err := send(ctx, request)
 	if err != nil {
 		sc.errorChannel <- err
 	}
 	return res
 }
+
+// load puts a previous request into the view so it can be changed and sent again
+func (sc siriClientView) load(request siri.ClientRequest) {
+	sc.selectTargetOf(request)
+	sc.urlInput.SetText(request.URL)
+	sc.headersArea.SetText(httputils.FormatHeaders(request.Header), false)
+	sc.requestArea.SetText(request.Body, false)
+}
+
+// selectTargetOf selects the push target of a pushed request and the server for all other requests,
+// so the loaded request is sent the same way again
+func (sc siriClientView) selectTargetOf(request siri.ClientRequest) {
+	if sc.targetDropdown == nil {
+		return
+	}
+	index := 0
+	if request.Push {
+		// the first target is used if the URL belongs to none, a push is never sent with the server credentials
+		index = 1
+		for i, target := range sc.siriClient.PushTargets {
+			if strings.HasPrefix(request.URL, target.URL) {
+				index = i + 1
+				break
+			}
+		}
+	}
+	if current, _ := sc.targetDropdown.GetCurrentOption(); current != index {
+		sc.targetDropdown.SetCurrentOption(index)
+	}
+}
@@ -1,6 +1,11 @@
 package ui
 
 import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/mszalbach/sirigo/internal/profile"
 	"github.com/mszalbach/sirigo/internal/siri"
 	"github.com/rivo/tview"
 )
@@ -10,32 +15,75 @@ type siriPage struct {
 	name           string
 	siriClientView siriClientView
 	siriServerView siriServerView
+	subscriptions  subscriptionsView
+	deliveries     *deliveriesView
+	history        *historyView
 	statusBar      statusBar
+	progress       progressView
+	siriClient     *siri.Client
+	app            tuiApp
+	errorChannel   chan<- error
+	// tlsOptions are merged with the TLS settings of a profile when switching to it
+	tlsOptions profile.TLSOptions
+	// cancelRequest aborts the request which is currently sent, it is nil if no request is pending
+	cancelRequest context.CancelFunc
+	mu            sync.Mutex
 }
 
 func newSiriPage(siriApp tuiApp, siriClient *siri.Client,
 	sendTemplates siri.TemplateCache,
 	responseTemplates siri.TemplateCache,
+	pushTemplates siri.TemplateCache,
+	profiles []profile.Profile,
+	tlsOptions profile.TLSOptions,
 ) *siriPage {
+	errorChannel := make(chan error, 5)
 	siriPage := siriPage{
-		name: "siri",
-		Flex: tview.NewFlex(),
+		name:         "siri",
+		Flex:         tview.NewFlex(),
+		siriClient:   siriClient,
+		app:          siriApp,
+		errorChannel: errorChannel,
+		tlsOptions:   tlsOptions,
 	}
 
 	// Building UI elements
-	errorChannel := make(chan error, 5)
 	siriPage.statusBar = newStatusBar(siriApp, errorChannel)
+	siriPage.progress = newProgressView(siriApp)
 	keymap := newKeymap()
-	siriPage.siriClientView = newSiriClientView(siriApp, siriClient, sendTemplates, errorChannel)
+	profileDropdown := siriPage.newProfileDropdown(profiles)
+	siriPage.siriClientView = newSiriClientView(siriApp, siriClient, sendTemplates, pushTemplates, errorChannel)
 	siriPage.siriServerView = newSiriServerView(siriApp, siriClient, responseTemplates, errorChannel)
+	siriPage.subscriptions = newSubscriptionsView(siriApp, siriClient.Subscriptions)
+	siriPage.deliveries = newDeliveriesView(siriApp, siriClient.Deliveries, siriPage.showDelivery)
+	siriPage.history = newHistoryView(siriApp, siriClient.History, siriPage.show, siriPage.replay)
+
+	// overdue heartbeats are shown like errors, so they are visible in the status bar
+	go func() {
+		for alarm := range siriClient.Heartbeats.Alarms {
+			errorChannel <- alarm
+		}
+	}()
 
 	// Building layout
+	clientFlex := tview.NewFlex().SetDirection(tview.FlexRow)
+	if profileDropdown != nil {
+		clientFlex.AddItem(profileDropdown, 2, 0, false)
+	}
+	clientFlex.
+		AddItem(siriPage.siriClientView, 0, 3, false).
+		AddItem(siriPage.subscriptions, 0, 1, false).
+		AddItem(siriPage.deliveries, 0, 1, false).
+		AddItem(siriPage.history, 0, 1, false)
+
 	bodyFlex := tview.NewFlex().
-		AddItem(siriPage.siriClientView, 0, 1, false).
+		AddItem(clientFlex, 0, 1, false).
 		AddItem(siriPage.siriServerView, 0, 1, false)
 
 	footerFlex := tview.NewFlex().
-		AddItem(keymap, 0, 1, false).AddItem(siriPage.statusBar, 0, 1, false)
+		AddItem(keymap, 0, 1, false).
+		AddItem(siriPage.progress, 0, 1, false).
+		AddItem(siriPage.statusBar, 0, 1, false)
 
 	siriPage.Flex.
 		SetDirection(tview.FlexRow).
@@ -45,11 +93,117 @@ func newSiriPage(siriApp tuiApp, siriClient *siri.Client,
 	return &siriPage
 }
 
+// newProfileDropdown creates the selector to switch between profiles or returns nil if there are none
+func (sp *siriPage) newProfileDropdown(profiles []profile.Profile) *tview.DropDown {
+	if len(profiles) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(profiles))
+	current := 0
+	for i, p := range profiles {
+		names = append(names, p.Name)
+		if p.URL == sp.siriClient.ServerURL && p.ClientRef == sp.siriClient.ClientRef {
+			current = i
+		}
+	}
+	dropdown := tview.NewDropDown().SetLabel("Profile: ").SetOptions(names, nil)
+	dropdown.SetCurrentOption(current)
+	dropdown.SetSelectedFunc(func(_ string, index int) {
+		sp.useProfile(profiles[index])
+	})
+
+	// register focus order
+	sp.app.register(dropdown)
+	return dropdown
+}
+
+// useProfile switches the client to the server of the profile
+func (sp *siriPage) useProfile(p profile.Profile) {
+	if err := p.Apply(sp.siriClient, sp.tlsOptions); err != nil {
+		sp.errorChannel <- err
+		return
+	}
+	sp.app.SetTitle(appTitle(p.ClientRef))
+	sp.siriClientView.useProfile(p)
+}
+
 func (sp *siriPage) send() {
+	ctx, finish, ok := sp.startRequest()
+	if !ok {
+		return
+	}
 	// async since request can take some time and block the UI
-	// better to inform the user about it
+	// the progress informs the user about it
+	go func() {
+		defer finish()
+		response := sp.siriClientView.send(ctx)
+		sp.siriServerView.setResponse(response)
+	}()
+}
+
+// startRequest returns the context of a new request which can be aborted with cancel
+// and a function to call when the request is done. Only one request can be pending, ok is false otherwise.
+func (sp *siriPage) startRequest() (ctx context.Context, finish func(), ok bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sp.mu.Lock()
+	pending := sp.cancelRequest != nil
+	if !pending {
+		sp.cancelRequest = cancel
+	}
+	sp.mu.Unlock()
+	if pending {
+		cancel()
+		sp.errorChannel <- errors.New("a request is already being sent, cancel it with Ctrl-C")
+		return nil, nil, false
+	}
+	stopProgress := sp.progress.start()
+	return ctx, func() {
+		stopProgress()
+		sp.mu.Lock()
+		defer sp.mu.Unlock()
+		cancel()
+		sp.cancelRequest = nil
+	}, true
+}
+
+// cancel aborts the request which is currently sent
+func (sp *siriPage) cancel() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.cancelRequest != nil {
+		sp.cancelRequest()
+	}
+}
+
+// show displays an exchange of the history again, outgoing requests are loaded into the client view
+func (sp *siriPage) show(exchange siri.Exchange) {
+	if exchange.Direction == siri.Outgoing {
+		sp.siriClientView.load(exchange.ClientRequest())
+	}
+	sp.siriServerView.showExchange(exchange)
+}
+
+// showDelivery displays the ServiceDelivery a delivery was received with
+func (sp *siriPage) showDelivery(delivery siri.DirectDelivery) {
+	sp.siriServerView.setRequest(delivery.Request)
+}
+
+// replay sends the request of an outgoing exchange again exactly like it was sent before
+func (sp *siriPage) replay(exchange siri.Exchange) {
+	if exchange.Direction != siri.Outgoing {
+		sp.errorChannel <- errors.New("only requests sent by the client can be replayed")
+		return
+	}
+	ctx, finish, ok := sp.startRequest()
+	if !ok {
+		return
+	}
 	go func() {
-		response := sp.siriClientView.send()
+		defer finish()
+		response, err := sp.siriClient.Replay(ctx, exchange.ClientRequest())
+		if err != nil {
+			sp.errorChannel <- err
+		}
 		sp.siriServerView.setResponse(response)
 	}()
 }
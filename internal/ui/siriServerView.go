@@ -1,15 +1,22 @@
 package ui
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
 
 	"github.com/mszalbach/sirigo/internal/siri"
+	"github.com/mszalbach/sirigo/internal/xsd"
 	"github.com/rivo/tview"
 )
 
 type siriServerView struct {
 	*tview.Flex
 	serverResponseTextView *codeTextView
+	serverResponseMetadata metadataView
+	serverRequestTextView  *codeTextView
+	serverRequestMetadata  metadataView
+	errorChannel           chan<- error
 }
 
 func newSiriServerView(
@@ -20,7 +27,9 @@ func newSiriServerView(
 ) siriServerView {
 	serverResponseTextView := newCodeTextView(app, "Server Response")
 	serverRequestTextView := newCodeTextView(app, "Server Request")
-	autoresponseDropdown := tview.NewDropDown().SetLabel("Client auto-response: ")
+	serverResponseMetadata := newMetadataView()
+	serverRequestMetadata := newMetadataView()
+	autoresponseDropdown := tview.NewDropDown().SetLabel("Default auto-response: ")
 
 	templateNames, err := responseTemplates.TemplateNames()
 	if err == nil {
@@ -40,29 +49,124 @@ func newSiriServerView(
 	})
 	autoresponseDropdown.SetCurrentOption(0)
 
+	fetchedCheckbox := tview.NewCheckbox().SetLabel("Fetched mode: ").SetChecked(siriClient.FetchedMode())
+	fetchedCheckbox.SetChangedFunc(siriClient.SetFetchedMode)
+
+	// register focus order
+	app.register(autoresponseDropdown, fetchedCheckbox)
+	rulesView := newAutoResponseRulesView(app, siriClient.AutoResponseRules, responseTemplates, errorChannel)
+	app.register(serverResponseMetadata, serverResponseTextView, serverRequestMetadata, serverRequestTextView)
+
 	siriServerFlex := tview.NewFlex().SetDirection(tview.FlexRow).
-		AddItem(autoresponseDropdown, 2, 0, false).
+		AddItem(autoresponseDropdown, 1, 0, false).
+		AddItem(fetchedCheckbox, 1, 0, false).
+		AddItem(rulesView, 6, 0, false).
+		AddItem(serverResponseMetadata, metadataHeight, 0, false).
 		AddItem(serverResponseTextView, 0, 2, false).
+		AddItem(serverRequestMetadata, metadataHeight, 0, false).
 		AddItem(serverRequestTextView, 0, 1, false)
 
-	go listenForServerRequests(serverRequestTextView, siriClient)
-
-	// register focus order
-	app.register(autoresponseDropdown, serverResponseTextView, serverRequestTextView)
-
-	return siriServerView{
+	siriServerView := siriServerView{
 		Flex:                   siriServerFlex,
 		serverResponseTextView: serverResponseTextView,
+		serverResponseMetadata: serverResponseMetadata,
+		serverRequestTextView:  serverRequestTextView,
+		serverRequestMetadata:  serverRequestMetadata,
+		errorChannel:           errorChannel,
 	}
+	go siriServerView.listenForServerRequests(siriClient)
+	go siriServerView.listenForFetchedResponses(siriClient)
+	return siriServerView
 }
 
-func listenForServerRequests(serverRequestTextView *codeTextView, siriClient *siri.Client) {
+func (sv siriServerView) listenForFetchedResponses(siriClient *siri.Client) {
+	for response := range siriClient.FetchedResponse {
+		sv.setResponse(response)
+	}
+}
+
+func (sv siriServerView) listenForServerRequests(siriClient *siri.Client) {
 	for req := range siriClient.ServerRequest {
-		body := fmt.Sprintf("<!-- %s%s -->\n%s", req.RemoteAddress, req.URL, req.Body)
-		serverRequestTextView.SetCode(body, req.Language)
+		// ServiceDeliveries are listed in the deliveries view and only shown here when selected there
+		if req.Message != nil && req.Message.ServiceDelivery != nil {
+			sv.reportViolations(validationError("server request", req.Violations))
+			continue
+		}
+		sv.setRequest(req)
 	}
 }
 
+func (sv siriServerView) setRequest(req siri.ServerRequest) {
+	body := fmt.Sprintf("<!-- %s%s -->\n%s", req.RemoteAddress, req.URL, req.Body)
+	sv.serverRequestTextView.SetCode(body, req.Language)
+	sv.serverRequestTextView.SetTitle(messageTitle("Server Request", req.MessageName()))
+	sv.serverRequestMetadata.setRequest(req)
+	sv.reportViolations(validationError("server request", req.Violations))
+}
+
 func (sv siriServerView) setResponse(response siri.ServerResponse) {
 	sv.serverResponseTextView.SetCode(response.Body, response.Language)
+	sv.serverResponseTextView.SetTitle(messageTitle("Server Response", response.MessageName()))
+	sv.serverResponseMetadata.setResponse(response)
+	sv.reportViolations(
+		validationError("request", response.RequestViolations),
+		validationError("response", response.Violations),
+	)
+}
+
+// reportViolations shows the schema violations in the status bar
+func (sv siriServerView) reportViolations(errs ...error) {
+	if err := errors.Join(errs...); err != nil {
+		sv.errorChannel <- err
+	}
+}
+
+// validationError returns nil if there are no violations, so it can be used with errors.Join
+func validationError(subject string, violations []xsd.Violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	return &siri.ValidationError{Subject: subject, Violations: violations}
+}
+
+// showExchange shows an exchange of the history like it was received
+func (sv siriServerView) showExchange(exchange siri.Exchange) {
+	if exchange.Direction == siri.Incoming {
+		sv.setRequest(siri.ServerRequest{
+			RemoteAddress: exchange.RemoteAddress,
+			Method:        exchange.Method,
+			URL:           exchange.URL,
+			Header:        exchange.RequestHeader,
+			Body:          cmp.Or(exchange.RequestBody, exchange.Error),
+			Language:      exchange.RequestLanguage,
+			Size:          len(exchange.RequestBody),
+			ReceivedAt:    exchange.Time,
+			Message:       siri.TryParseMessage(exchange.RequestBody),
+			VDVMessage:    siri.ParseVDVMessage(exchange.RequestBody),
+		})
+		return
+	}
+	if exchange.Error != "" {
+		sv.setResponse(siri.ServerResponse{Body: exchange.Error, Language: "plaintext"})
+		return
+	}
+	sv.setResponse(siri.ServerResponse{
+		Body:       exchange.ResponseBody,
+		Status:     exchange.Status,
+		Header:     exchange.ResponseHeader,
+		Language:   exchange.ResponseLanguage,
+		Duration:   exchange.Duration,
+		Size:       len(exchange.ResponseBody),
+		ReceivedAt: exchange.Time.Add(exchange.Duration),
+		Message:    siri.TryParseMessage(exchange.ResponseBody),
+		VDVMessage: siri.ParseVDVMessage(exchange.ResponseBody),
+	})
+}
+
+// messageTitle adds the message name to the title if the body is a known SIRI or VDV453 message
+func messageTitle(title string, name string) string {
+	if name != "" {
+		return fmt.Sprintf("%s (%s)", title, name)
+	}
+	return title
 }
@@ -31,6 +31,11 @@ func (app *AppMock) Suspend(_ func()) bool {
 	return true
 }
 
+func (app *AppMock) SetTitle(_ string) *tview.Application {
+	// not needed for this test
+	return nil
+}
+
 var app = new(AppMock)
 
 func newTestScreen(t *testing.T) tcell.SimulationScreen {
@@ -18,6 +18,8 @@ var (
 		"orange":     tcell.GetColor("#ffb86c"),
 		"yellow":     tcell.GetColor("#f1fa8c"),
 		"pink":       tcell.GetColor("#ff79c6"),
+		"green":      tcell.GetColor("#50fa7b"),
+		"red":        tcell.GetColor("#ff5555"),
 		"comment":    tcell.GetColor("#6272a4"),
 	}
 
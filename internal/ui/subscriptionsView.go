@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mszalbach/sirigo/internal/siri"
+	"github.com/rivo/tview"
+)
+
+var subscriptionColumns = []string{"ID", "Subscriber", "Service", "Status", "Deliveries", "Terminates", "URL"}
+
+type subscriptionsView struct {
+	*tview.Table
+}
+
+func newSubscriptionsView(app tuiApp, subscriptions *siri.Subscriptions) subscriptionsView {
+	table := tview.NewTable().SetFixed(1, 0).SetSelectable(true, false)
+	table.SetBorder(true).SetTitle("Subscriptions")
+
+	view := subscriptionsView{Table: table}
+	view.update(subscriptions.List())
+
+	go func() {
+		for range subscriptions.Changed {
+			app.QueueUpdateDraw(func() {
+				view.update(subscriptions.List())
+			})
+		}
+	}()
+
+	// register focus order
+	app.register(table)
+
+	return view
+}
+
+func (sv subscriptionsView) update(subscriptions []siri.Subscription) {
+	sv.Clear()
+	for column, title := range subscriptionColumns {
+		sv.SetCell(0, column, tview.NewTableCell(title).SetSelectable(false).SetTextColor(colors["purple"]))
+	}
+
+	now := time.Now()
+	for i, subscription := range subscriptions {
+		row := i + 1
+		status := string(subscription.Status)
+		if subscription.Expired(now) {
+			status = "expired"
+		}
+		if subscription.ErrorText != "" {
+			status += ": " + subscription.ErrorText
+		}
+		sv.SetCellSimple(row, 0, subscription.Identifier)
+		sv.SetCellSimple(row, 1, subscription.SubscriberRef)
+		sv.SetCellSimple(row, 2, subscription.ServiceType)
+		sv.SetCell(row, 3, tview.NewTableCell(status).SetTextColor(subscriptionStatusColor(subscription, now)))
+		sv.SetCellSimple(row, 4, deliveriesText(subscription))
+		sv.SetCellSimple(row, 5, subscription.InitialTerminationTime.Local().Format(time.DateTime))
+		sv.SetCellSimple(row, 6, subscription.URL)
+	}
+}
+
+// deliveriesText shows how many ServiceDeliveries were received directly and when the last one arrived
+func deliveriesText(subscription siri.Subscription) string {
+	if subscription.Deliveries == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d (%s)", subscription.Deliveries, subscription.LastDelivery.Local().Format(time.TimeOnly))
+}
+
+func subscriptionStatusColor(subscription siri.Subscription, now time.Time) tcell.Color {
+	switch {
+	case subscription.Expired(now):
+		return colors["orange"]
+	case subscription.Status == siri.SubscriptionActive:
+		return colors["green"]
+	case subscription.Status == siri.SubscriptionRejected:
+		return colors["red"]
+	case subscription.Status == siri.SubscriptionTerminated:
+		return colors["comment"]
+	}
+	return colors["foreground"]
+}
@@ -0,0 +1,269 @@
+// Package xsd validates XML documents against a subset of XML Schema.
+// It checks that elements are declared, only contain the declared child elements and that required children exist.
+// Order, occurrence limits, attributes and values are not checked.
+package xsd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// xsdNamespace is the namespace of XML Schema itself, all its types are simple types
+const xsdNamespace = "http://www.w3.org/2001/XMLSchema"
+
+// node is a generic element of a schema file
+type node struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []node     `xml:",any"`
+}
+
+func (n node) attr(name string) string {
+	for _, attr := range n.Attrs {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func (n node) is(local string) bool {
+	return n.XMLName.Space == xsdNamespace && n.XMLName.Local == local
+}
+
+type particleKind int
+
+const (
+	elementParticle particleKind = iota
+	groupParticle
+	anyParticle
+	sequenceParticle
+	choiceParticle
+)
+
+// particle is a part of a content model like an element, a group reference, a sequence or a choice
+type particle struct {
+	kind      particleKind
+	minOccurs int
+	// element is set for elements, either a local declaration or a reference to a global one
+	element *elementDecl
+	ref     xml.Name
+	// children of sequences and choices
+	children []particle
+}
+
+type elementDecl struct {
+	name              xml.Name
+	typeName          xml.Name
+	inlineType        *complexType
+	substitutionGroup xml.Name
+	abstract          bool
+}
+
+type complexType struct {
+	// simple types and complex types with simple content only contain text
+	simple bool
+	base   xml.Name
+	// extension is true if the content of the base type is extended, false for restrictions
+	extension bool
+	content   *particle
+}
+
+// Schema is a set of schema files used to validate XML documents
+type Schema struct {
+	elements      map[xml.Name]*elementDecl
+	types         map[xml.Name]*complexType
+	groups        map[xml.Name]*particle
+	substitutions map[xml.Name][]xml.Name
+	models        map[*complexType]*contentModel
+}
+
+// Load reads all .xsd files within the folder and its subfolders
+func Load(dir string) (*Schema, error) {
+	schema := &Schema{
+		elements:      map[xml.Name]*elementDecl{},
+		types:         map[xml.Name]*complexType{},
+		groups:        map[xml.Name]*particle{},
+		substitutions: map[xml.Name][]xml.Name{},
+		models:        map[*complexType]*contentModel{},
+	}
+	found := false
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.EqualFold(filepath.Ext(path), ".xsd") {
+			return err
+		}
+		found = true
+		return schema.loadFile(path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no .xsd files found in %s", dir)
+	}
+	for _, element := range schema.elements {
+		if element.substitutionGroup.Local != "" {
+			schema.substitutions[element.substitutionGroup] = append(
+				schema.substitutions[element.substitutionGroup],
+				element.name,
+			)
+		}
+	}
+	return schema, nil
+}
+
+func (s *Schema) loadFile(path string) error {
+	content, err := os.ReadFile(path) //nolint gosec // the schema folder is chosen by the user
+	if err != nil {
+		return err
+	}
+	var root node
+	if err := xml.Unmarshal(content, &root); err != nil {
+		return fmt.Errorf("could not parse schema %s: %w", path, err)
+	}
+	if !root.is("schema") {
+		return fmt.Errorf("%s is not an XML schema", path)
+	}
+
+	f := schemaFile{
+		targetNamespace: root.attr("targetNamespace"),
+		qualified:       root.attr("elementFormDefault") == "qualified",
+		prefixes:        map[string]string{},
+	}
+	for _, attr := range root.Attrs {
+		switch {
+		case attr.Name.Space == "xmlns":
+			f.prefixes[attr.Name.Local] = attr.Value
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			f.prefixes[""] = attr.Value
+		}
+	}
+
+	for _, child := range root.Children {
+		name := xml.Name{Space: f.targetNamespace, Local: child.attr("name")}
+		switch {
+		case child.is("element"):
+			element := f.elementDecl(child)
+			element.name = name
+			s.elements[name] = element
+		case child.is("complexType"):
+			s.types[name] = f.complexType(child)
+		case child.is("simpleType"):
+			s.types[name] = &complexType{simple: true}
+		case child.is("group"):
+			if content := f.content(child); content != nil {
+				s.groups[name] = content
+			}
+		}
+	}
+	return nil
+}
+
+// schemaFile contains the context needed to resolve names within a schema file
+type schemaFile struct {
+	targetNamespace string
+	qualified       bool
+	prefixes        map[string]string
+}
+
+// qname resolves a prefixed name like siri:SiriType
+func (f schemaFile) qname(value string) xml.Name {
+	if value == "" {
+		return xml.Name{}
+	}
+	prefix, local, found := strings.Cut(value, ":")
+	if !found {
+		return xml.Name{Space: f.prefixes[""], Local: value}
+	}
+	return xml.Name{Space: f.prefixes[prefix], Local: local}
+}
+
+func (f schemaFile) elementDecl(n node) *elementDecl {
+	element := &elementDecl{
+		typeName:          f.qname(n.attr("type")),
+		substitutionGroup: f.qname(n.attr("substitutionGroup")),
+		abstract:          n.attr("abstract") == "true",
+	}
+	for _, child := range n.Children {
+		switch {
+		case child.is("complexType"):
+			element.inlineType = f.complexType(child)
+		case child.is("simpleType"):
+			element.inlineType = &complexType{simple: true}
+		}
+	}
+	return element
+}
+
+func (f schemaFile) complexType(n node) *complexType {
+	for _, child := range n.Children {
+		switch {
+		case child.is("simpleContent"):
+			return &complexType{simple: true}
+		case child.is("complexContent"):
+			for _, derivation := range child.Children {
+				if derivation.is("extension") || derivation.is("restriction") {
+					return &complexType{
+						base:      f.qname(derivation.attr("base")),
+						extension: derivation.is("extension"),
+						content:   f.content(derivation),
+					}
+				}
+			}
+		}
+	}
+	return &complexType{content: f.content(n)}
+}
+
+// content returns the first model group of the node or nil if it has none
+func (f schemaFile) content(n node) *particle {
+	for _, child := range n.Children {
+		if p, ok := f.particle(child); ok {
+			return &p
+		}
+	}
+	return nil
+}
+
+func (f schemaFile) particle(n node) (particle, bool) {
+	p := particle{minOccurs: 1}
+	if minOccurs, err := strconv.Atoi(n.attr("minOccurs")); err == nil {
+		p.minOccurs = minOccurs
+	}
+	switch {
+	case n.is("element") && n.attr("ref") != "":
+		p.kind = elementParticle
+		p.ref = f.qname(n.attr("ref"))
+	case n.is("element"):
+		p.kind = elementParticle
+		p.element = f.elementDecl(n)
+		p.element.name = xml.Name{Local: n.attr("name")}
+		if f.qualified || n.attr("form") == "qualified" {
+			p.element.name.Space = f.targetNamespace
+		}
+	case n.is("group"):
+		p.kind = groupParticle
+		p.ref = f.qname(n.attr("ref"))
+	case n.is("any"):
+		p.kind = anyParticle
+	case n.is("sequence"), n.is("all"):
+		p.kind = sequenceParticle
+	case n.is("choice"):
+		p.kind = choiceParticle
+	default:
+		return particle{}, false
+	}
+	if p.kind == sequenceParticle || p.kind == choiceParticle {
+		for _, child := range n.Children {
+			if childParticle, ok := f.particle(child); ok {
+				p.children = append(p.children, childParticle)
+			}
+		}
+	}
+	return p, true
+}
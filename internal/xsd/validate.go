@@ -0,0 +1,343 @@
+package xsd
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Violation is a part of a document which does not match the schema
+type Violation struct {
+	Line    int
+	Path    string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("line %d %s: %s", v.Line, v.Path, v.Message)
+}
+
+// contentModel describes which child elements are allowed within an element
+type contentModel struct {
+	// permissive is set if the content is unknown, for example because of a missing schema file
+	permissive bool
+	// simple is set if only text is allowed
+	simple bool
+	// wildcard is set if any other element is allowed too
+	wildcard bool
+	// elements are the allowed child elements by name, the declaration is nil if it is unknown
+	elements map[string]*elementDecl
+	// required contains groups of element names, one element of each group must exist
+	required [][]string
+}
+
+// instance is an element of the validated document
+type instance struct {
+	name     xml.Name
+	line     int
+	children []*instance
+}
+
+// Validate checks the document against the schema and returns all violations.
+// An error is returned if the document is not well-formed XML.
+func (s *Schema) Validate(document string) ([]Violation, error) {
+	root, err := parseInstance(document)
+	if err != nil {
+		return nil, err
+	}
+	decl := s.globalElement(root.name)
+	if decl == nil {
+		return []Violation{{
+			Line:    root.line,
+			Path:    "/" + root.name.Local,
+			Message: fmt.Sprintf("unknown root element %q", root.name.Local),
+		}}, nil
+	}
+	var violations []Violation
+	s.validateElement(root, decl, "/"+root.name.Local, &violations)
+	return violations, nil
+}
+
+func (s *Schema) validateElement(element *instance, decl *elementDecl, path string, violations *[]Violation) {
+	model := s.elementModel(decl)
+	if model.permissive {
+		return
+	}
+	if model.simple {
+		if len(element.children) > 0 {
+			*violations = append(*violations, Violation{
+				Line:    element.children[0].line,
+				Path:    path,
+				Message: fmt.Sprintf("element %q must only contain text", element.name.Local),
+			})
+		}
+		return
+	}
+
+	present := map[string]bool{}
+	for _, child := range element.children {
+		present[child.name.Local] = true
+		childPath := path + "/" + child.name.Local
+		childDecl, allowed := model.elements[child.name.Local]
+		switch {
+		case allowed && childDecl != nil:
+			s.validateElement(child, childDecl, childPath, violations)
+		case !allowed && !model.wildcard:
+			*violations = append(*violations, Violation{
+				Line:    child.line,
+				Path:    childPath,
+				Message: fmt.Sprintf("unknown element %q in %q", child.name.Local, element.name.Local),
+			})
+		}
+	}
+
+	for _, names := range model.required {
+		if !containsAny(present, names) {
+			*violations = append(*violations, Violation{
+				Line:    element.line,
+				Path:    path,
+				Message: missingMessage(names, element.name.Local),
+			})
+		}
+	}
+}
+
+func containsAny(present map[string]bool, names []string) bool {
+	for _, name := range names {
+		if present[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func missingMessage(names []string, parent string) string {
+	if len(names) == 1 {
+		return fmt.Sprintf("missing required element %q in %q", names[0], parent)
+	}
+	return fmt.Sprintf("missing one of %q in %q", names, parent)
+}
+
+// globalElement returns the declaration of a root element.
+// Elements without a matching namespace are looked up by their local name.
+func (s *Schema) globalElement(name xml.Name) *elementDecl {
+	if decl, ok := s.elements[name]; ok {
+		return decl
+	}
+	for declName, decl := range s.elements {
+		if declName.Local == name.Local && !decl.abstract {
+			return decl
+		}
+	}
+	return nil
+}
+
+func (s *Schema) elementModel(decl *elementDecl) *contentModel {
+	if decl.inlineType != nil {
+		return s.model(decl.inlineType)
+	}
+	return s.typeModel(decl.typeName)
+}
+
+func (s *Schema) typeModel(name xml.Name) *contentModel {
+	switch {
+	case name.Local == "" || (name.Space == xsdNamespace && name.Local == "anyType"):
+		return &contentModel{permissive: true}
+	case name.Space == xsdNamespace:
+		return &contentModel{simple: true}
+	}
+	if t, ok := s.types[name]; ok {
+		return s.model(t)
+	}
+	return &contentModel{permissive: true}
+}
+
+func (s *Schema) model(t *complexType) *contentModel {
+	if model, ok := s.models[t]; ok {
+		return model
+	}
+	model := &contentModel{elements: map[string]*elementDecl{}}
+	// cached before it is filled to stop recursive type definitions
+	s.models[t] = model
+
+	if t.simple {
+		model.simple = true
+		return model
+	}
+	if t.extension {
+		base := s.typeModel(t.base)
+		model.permissive = base.permissive
+		model.simple = base.simple && t.content == nil
+		model.wildcard = base.wildcard
+		for name, decl := range base.elements {
+			model.elements[name] = decl
+		}
+		model.required = append(model.required, base.required...)
+	}
+	if t.content != nil {
+		s.addParticle(model, *t.content, true)
+	}
+	return model
+}
+
+// addParticle adds the elements of the particle to the model.
+// required is false if the particle is within an optional particle or a choice.
+func (s *Schema) addParticle(model *contentModel, p particle, required bool) {
+	required = required && p.minOccurs > 0
+	switch p.kind {
+	case elementParticle:
+		decls := s.particleElements(p)
+		if len(decls) == 0 {
+			// reference into a schema which is not loaded
+			model.elements[p.ref.Local] = nil
+		}
+		for _, decl := range decls {
+			model.elements[decl.name.Local] = decl
+		}
+		if required {
+			model.required = append(model.required, s.names(p))
+		}
+	case groupParticle:
+		if group, ok := s.groups[p.ref]; ok {
+			s.addParticle(model, *group, required)
+		} else {
+			model.wildcard = true
+		}
+	case anyParticle:
+		model.wildcard = true
+	case sequenceParticle:
+		for _, child := range p.children {
+			s.addParticle(model, child, required)
+		}
+	case choiceParticle:
+		for _, child := range p.children {
+			s.addParticle(model, child, false)
+		}
+		if required && !s.optional(p) {
+			model.required = append(model.required, s.names(p))
+		}
+	}
+}
+
+// particleElements returns the declarations of all elements which can be used for an element particle
+func (s *Schema) particleElements(p particle) []*elementDecl {
+	if p.element != nil {
+		return []*elementDecl{p.element}
+	}
+	var decls []*elementDecl
+	queue := []xml.Name{p.ref}
+	seen := map[xml.Name]bool{}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if decl, ok := s.elements[name]; ok && !decl.abstract {
+			decls = append(decls, decl)
+		}
+		queue = append(queue, s.substitutions[name]...)
+	}
+	return decls
+}
+
+// names returns the names of all elements within the particle
+func (s *Schema) names(p particle) []string {
+	switch p.kind {
+	case elementParticle:
+		decls := s.particleElements(p)
+		if len(decls) == 0 {
+			return []string{p.ref.Local}
+		}
+		names := make([]string, 0, len(decls))
+		for _, decl := range decls {
+			names = append(names, decl.name.Local)
+		}
+		return names
+	case groupParticle:
+		if group, ok := s.groups[p.ref]; ok {
+			return s.names(*group)
+		}
+	case sequenceParticle, choiceParticle:
+		var names []string
+		for _, child := range p.children {
+			names = append(names, s.names(child)...)
+		}
+		return names
+	}
+	return nil
+}
+
+// optional reports whether the particle can be left out completely
+func (s *Schema) optional(p particle) bool {
+	if p.minOccurs == 0 {
+		return true
+	}
+	switch p.kind {
+	case groupParticle:
+		group, ok := s.groups[p.ref]
+		return !ok || s.optional(*group)
+	case anyParticle:
+		return true
+	case sequenceParticle:
+		for _, child := range p.children {
+			if !s.optional(child) {
+				return false
+			}
+		}
+		return true
+	case choiceParticle:
+		for _, child := range p.children {
+			if s.optional(child) {
+				return true
+			}
+		}
+		return len(p.children) == 0
+	}
+	return false
+}
+
+// parseInstance reads the element tree of the document with the line of each element
+func parseInstance(document string) (*instance, error) {
+	decoder := xml.NewDecoder(strings.NewReader(document))
+	var root *instance
+	var stack []*instance
+	line := 1
+	lastOffset := 0
+	for {
+		offset := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line += strings.Count(document[lastOffset:offset], "\n")
+		lastOffset = offset
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			element := &instance{name: t.Name, line: line}
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, errors.New("document has more than one root element")
+				}
+				root = element
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, element)
+			}
+			stack = append(stack, element)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if root == nil {
+		return nil, errors.New("document has no root element")
+	}
+	return root, nil
+}
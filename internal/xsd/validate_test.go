@@ -0,0 +1,145 @@
+package xsd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTestSchema(t *testing.T) *Schema {
+	t.Helper()
+	schema, err := Load("testdata/schemas")
+	require.NoError(t, err)
+	return schema
+}
+
+func Test_valid_documents_have_no_violations(t *testing.T) {
+	testCases := []struct {
+		name     string
+		document string
+	}{
+		{
+			name: "extension with group",
+			document: `<Siri xmlns="http://www.siri.org.uk/siri" version="2.1">
+	<ServiceRequest>
+		<RequestTimestamp>2004-12-17T09:30:47Z</RequestTimestamp>
+		<RequestorRef>client</RequestorRef>
+		<StopMonitoringRequest>
+			<MonitoringRef>HLT</MonitoringRef>
+		</StopMonitoringRequest>
+		<Extensions><Anything><Else/></Anything></Extensions>
+	</ServiceRequest>
+</Siri>`,
+		},
+		{
+			name: "substitution group",
+			document: `<Siri>
+	<CheckStatusRequest>
+		<RequestTimestamp>2004-12-17T09:30:47Z</RequestTimestamp>
+	</CheckStatusRequest>
+</Siri>`,
+		},
+		{
+			name: "wildcard",
+			document: `<Siri>
+	<ServiceRequest>
+		<RequestTimestamp>2004-12-17T09:30:47Z</RequestTimestamp>
+		<VehicleMonitoringRequest><VehicleRef>1</VehicleRef></VehicleMonitoringRequest>
+	</ServiceRequest>
+</Siri>`,
+		},
+	}
+	schema := loadTestSchema(t)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// When
+			violations, err := schema.Validate(tc.document)
+
+			// Then
+			require.NoError(t, err)
+			assert.Empty(t, violations)
+		})
+	}
+}
+
+func Test_violations_are_reported_with_line_and_path(t *testing.T) {
+	// Given
+	schema := loadTestSchema(t)
+	document := `<Siri xmlns="http://www.siri.org.uk/siri">
+	<ServiceRequest>
+		<RequestorRef>client</RequestorRef>
+		<StopMonitoringRequest>
+			<MonitoringRef><Ref>HLT</Ref></MonitoringRef>
+			<MaximumStopVisit>3</MaximumStopVisit>
+		</StopMonitoringRequest>
+	</ServiceRequest>
+</Siri>`
+
+	// When
+	violations, err := schema.Validate(document)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, []Violation{
+		{
+			Line:    5,
+			Path:    "/Siri/ServiceRequest/StopMonitoringRequest/MonitoringRef",
+			Message: `element "MonitoringRef" must only contain text`,
+		},
+		{
+			Line:    6,
+			Path:    "/Siri/ServiceRequest/StopMonitoringRequest/MaximumStopVisit",
+			Message: `unknown element "MaximumStopVisit" in "StopMonitoringRequest"`,
+		},
+		{
+			Line:    2,
+			Path:    "/Siri/ServiceRequest",
+			Message: `missing required element "RequestTimestamp" in "ServiceRequest"`,
+		},
+	}, violations)
+}
+
+func Test_missing_choice_is_reported(t *testing.T) {
+	// Given
+	schema := loadTestSchema(t)
+
+	// When
+	violations, err := schema.Validate(`<Siri></Siri>`)
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, `missing one of ["ServiceRequest" "CheckStatusRequest"] in "Siri"`, violations[0].Message)
+}
+
+func Test_unknown_root_element_is_reported(t *testing.T) {
+	// Given
+	schema := loadTestSchema(t)
+
+	// When
+	violations, err := schema.Validate(`<Sirri/>`)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, []Violation{{Line: 1, Path: "/Sirri", Message: `unknown root element "Sirri"`}}, violations)
+}
+
+func Test_malformed_documents_are_an_error(t *testing.T) {
+	// Given
+	schema := loadTestSchema(t)
+
+	// When
+	_, err := schema.Validate(`<Siri><ServiceRequest></Siri>`)
+
+	// Then
+	assert.Error(t, err)
+}
+
+func Test_folder_without_schemas_is_an_error(t *testing.T) {
+	// When
+	_, err := Load(t.TempDir())
+
+	// Then
+	assert.ErrorContains(t, err, "no .xsd files found")
+}